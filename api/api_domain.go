@@ -0,0 +1,261 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"FaRyuk/internal/db"
+	"FaRyuk/internal/group"
+	"FaRyuk/internal/helper"
+	"FaRyuk/internal/job"
+	"FaRyuk/internal/operations"
+	"FaRyuk/internal/types"
+	"FaRyuk/pkg"
+
+	"github.com/gorilla/mux"
+)
+
+// maxImportSize : largest subdomain dataset accepted by an import
+const maxImportSize = 64 << 20
+
+func addDomainEndpoints(secure *mux.Router) {
+	secure.HandleFunc("/api/domain/{domain}/import", importSubdomains).Methods("POST")
+	secure.HandleFunc("/api/domain-results", getDomainResults).Methods("GET")
+	secure.HandleFunc("/api/count-domain-results", countDomainResults).Methods("GET")
+	secure.HandleFunc("/api/domain-result/{id}", getDomainResultByID).Methods("GET")
+}
+
+// canReadDomainResult : tells whether a user owns a domain result or belongs to its group
+func canReadDomainResult(dbHandler *db.Handler, username, idUser string, result *types.DomainResult) bool {
+	if username == adminUsername || result.Owner == idUser {
+		return true
+	}
+	user := dbHandler.GetUserByID(idUser)
+	return user != nil && helper.ContainsStr(group.ToIDsArray(user.Groups), result.OwnerGroup)
+}
+
+// domainResultsScope : returns the owner and the groups the domain results read by a user are restricted to, none
+// for the admin
+func domainResultsScope(dbHandler *db.Handler, username, idUser string) (string, []string) {
+	if username == adminUsername {
+		return "", nil
+	}
+	user := dbHandler.GetUserByID(idUser)
+	if user == nil {
+		return idUser, nil
+	}
+	return idUser, group.ToIDsArray(user.Groups)
+}
+
+func getDomainResults(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	search := ""
+	if searchSlice, ok := query["search"]; ok {
+		search = searchSlice[0] + " "
+	}
+	searchMap := helper.Tokenize(search)
+
+	username, idUser, err := getIdentity(&w, r)
+	if err != nil {
+		return
+	}
+
+	pageSize := 10
+	if pageSizeSlice, ok := query["size"]; ok {
+		pageSize, _ = strconv.Atoi(pageSizeSlice[0])
+	}
+	offset := 1
+	if offsetSlice, ok := query["offset"]; ok {
+		offset, _ = strconv.Atoi(offsetSlice[0])
+	}
+
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	if searchMap["group"] != "" {
+		group, err := dbHandler.GetGroupsByName(searchMap["group"])
+		if err != nil {
+			writeInternalError(&w, dbError)
+			return
+		}
+		searchMap["group"] = group.ID
+	}
+
+	owner, groups := domainResultsScope(dbHandler, username, idUser)
+	results, err := dbHandler.GetDomainResultsBySearch(searchMap, owner, groups, offset, pageSize)
+	if err != nil {
+		writeInternalError(&w, dbError)
+		return
+	}
+	writeObject(&w, results)
+}
+
+func countDomainResults(w http.ResponseWriter, r *http.Request) {
+	search := ""
+	if searchSlice, ok := r.URL.Query()["search"]; ok {
+		search = searchSlice[0] + " "
+	}
+	searchMap := helper.Tokenize(search)
+
+	username, idUser, err := getIdentity(&w, r)
+	if err != nil {
+		return
+	}
+
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	if searchMap["group"] != "" {
+		group, err := dbHandler.GetGroupsByName(searchMap["group"])
+		if err != nil {
+			writeInternalError(&w, dbError)
+			return
+		}
+		searchMap["group"] = group.ID
+	}
+
+	owner, groups := domainResultsScope(dbHandler, username, idUser)
+	cnt, err := dbHandler.CountDomainResultsBySearch(searchMap, owner, groups)
+	if err != nil {
+		writeInternalError(&w, dbError)
+		return
+	}
+	writeObject(&w, cnt)
+}
+
+// getDomainResultByID : returns a domain result, its subdomains link to the results of their scans by the owner
+func getDomainResultByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	username, idUser, err := getIdentity(&w, r)
+	if err != nil {
+		return
+	}
+
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	result := dbHandler.GetDomainResultByID(id)
+	if result == nil {
+		writeNotFound(&w, "Domain result not found")
+		return
+	}
+	if !canReadDomainResult(dbHandler, username, idUser, result) {
+		writeForbidden(&w, "Not allowed to read this domain result")
+		return
+	}
+
+	names := make([]string, len(result.Subdomains))
+	for idx, subdomain := range result.Subdomains {
+		names[idx] = subdomain.Name
+	}
+	hostResults, err := dbHandler.GetResultsByHostsAndOwner(names, result.Owner)
+	if err != nil {
+		writeInternalError(&w, dbError)
+		return
+	}
+	resultIDs := make(map[string]string)
+	for _, hostResult := range hostResults {
+		resultIDs[hostResult.Host] = hostResult.ID
+	}
+	for idx := range result.Subdomains {
+		result.Subdomains[idx].ResultID = resultIDs[result.Subdomains[idx].Name]
+	}
+
+	writeObject(&w, *result)
+}
+
+// importSubdomains : adds the subdomains of the dataset in the body to the domain result, the ones imported are
+// scanned when a portlist and a dirlist are given. With resolve the import is queued as a job
+func importSubdomains(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	domain := strings.ToLower(strings.TrimSuffix(vars["domain"], "."))
+	query := r.URL.Query()
+	resolve := query.Get("resolve") == "true"
+	groupID := query.Get("idGroup")
+
+	_, idUser, err := getIdentity(&w, r)
+	if err != nil {
+		return
+	}
+
+	if domain == "" {
+		writeInternalError(&w, "Please provide a valid domain")
+		return
+	}
+	portlist, dirlist := query.Get("portlist"), query.Get("dirlist")
+	if portlist != "" && dirlist == "" {
+		writeInternalError(&w, "Please provide a valid dirlist")
+		return
+	}
+
+	names, err := pkg.ParseSubdomains(http.MaxBytesReader(w, r.Body, maxImportSize), query.Get("format"), domain)
+	if errors.Is(err, pkg.ErrUnknownFormat) {
+		writeInternalError(&w, "Please provide a valid format (list, jsonl or csv)")
+		return
+	}
+	if err != nil {
+		writeInternalError(&w, "Could not parse dataset : "+err.Error())
+		return
+	}
+
+	params := types.JobParams{
+		Domain:   domain,
+		Portlist: portlist,
+		Dirlist:  dirlist,
+		Scanners: query["scanner"],
+	}
+
+	// Resolving can take long, the dataset is imported by a job
+	if resolve {
+		params.Dataset, err = operations.StoreImportDataset(names)
+		if err != nil {
+			writeInternalError(&w, "Could not store dataset")
+			return
+		}
+		if submitJob(&w, job.KindImport, idUser, groupID, params) != nil {
+			return
+		}
+		writeObject(&w, "Import started")
+		return
+	}
+
+	summary, err := operations.ImportSubdomains(r.Context(), idUser, groupID, domain, names, false)
+	if err != nil {
+		writeInternalError(&w, "Could not import subdomains")
+		return
+	}
+
+	if portlist != "" {
+		err = scanMultipleAndSave(idUser, summary.Imported, groupID, params)
+		if err != nil {
+			writeInternalError(&w, "Could not queue scans : "+err.Error())
+			return
+		}
+		summary.Scanned = len(summary.Imported)
+	}
+
+	writeObject(&w, summary)
+}
+
+// runImportJob : imports with resolution a dataset stored by importSubdomains and queues the scans of the subdomains
+// imported
+func runImportJob(ctx context.Context, j *types.Job) error {
+	p := j.Params
+	summary, err := operations.ImportStoredSubdomains(ctx, j.Owner, j.OwnerGroup, p.Domain, p.Dataset)
+	if err != nil {
+		return err
+	}
+	log.Printf("[+] Import of %s : %d subdomains, %d already found, %d not resolving, %d imported",
+		p.Domain, summary.Total, summary.Duplicates, summary.Unresolved, len(summary.Imported))
+	if p.Portlist == "" {
+		return nil
+	}
+	p.Domain, p.Dataset = "", ""
+	return jobQueue.SubmitAll(newJobs(job.KindScan, j.ID, j.Owner, j.OwnerGroup, summary.Imported, p))
+}
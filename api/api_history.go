@@ -1,21 +1,35 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"FaRyuk/internal/db"
+	"FaRyuk/internal/events"
 	"FaRyuk/internal/group"
 	"FaRyuk/internal/helper"
 	"FaRyuk/internal/types"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 )
 
+// streamKeepAlive : interval between keep-alive messages on idle streams
+const streamKeepAlive = 15 * time.Second
+
+var upgrader = websocket.Upgrader{}
+
 func addHistoryEndpoints(secure *mux.Router) {
 	secure.HandleFunc("/api/get-history", getHistory).Methods("GET")
 	secure.HandleFunc("/api/count-history", countHistory).Methods("GET")
 	secure.HandleFunc("/api/history/{id}", getHistoryRecordByID).Methods("GET")
+	secure.HandleFunc("/api/history/{id}/events", getHistoryEvents).Methods("GET")
+	secure.HandleFunc("/api/history/{id}/stream", streamHistory).Methods("GET")
+	secure.HandleFunc("/api/history/{id}/ws", streamHistoryWS).Methods("GET")
 	secure.HandleFunc("/api/history/{id}", deleteHistory).Methods("DELETE")
 }
 
@@ -75,9 +89,36 @@ func getHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = fillStages(dbHandler, results)
+	if err != nil {
+		writeInternalError(&w, dbError)
+		return
+	}
 	writeObject(&w, results)
 }
 
+// fillStages : computes the duration of the stages of history records from their events
+func fillStages(dbHandler *db.Handler, records []types.HistoryRecord) error {
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+
+	evts, err := dbHandler.GetHistoryEvents(ids)
+	if err != nil {
+		return err
+	}
+
+	byRecord := make(map[string][]types.ScanEvent)
+	for _, e := range evts {
+		byRecord[e.HistoryID] = append(byRecord[e.HistoryID], e)
+	}
+	for idx := range records {
+		records[idx].Stages = events.Durations(byRecord[records[idx].ID])
+	}
+	return nil
+}
+
 func countHistory(w http.ResponseWriter, r *http.Request) {
 	var cntRecords int
 	var err error
@@ -111,6 +152,15 @@ func countHistory(w http.ResponseWriter, r *http.Request) {
 	writeObject(&w, cntRecords)
 }
 
+// canReadHistoryRecord : checks that a user owns a history record or belongs to its group, the admin reads them all
+func canReadHistoryRecord(dbHandler *db.Handler, username, idUser string, record *types.HistoryRecord) bool {
+	if username == adminUsername || record.Owner == idUser {
+		return true
+	}
+	user := dbHandler.GetUserByID(idUser)
+	return user != nil && helper.ContainsStr(group.ToIDsArray(user.Groups), record.OwnerGroup)
+}
+
 func getHistoryRecordByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -123,7 +173,44 @@ func getHistoryRecordByID(w http.ResponseWriter, r *http.Request) {
 		writeInternalError(&w, "Cannot retrieve history record")
 		return
 	}
-	writeObject(&w, result)
+
+	records := []types.HistoryRecord{result}
+	err = fillStages(dbHandler, records)
+	if err != nil {
+		writeInternalError(&w, dbError)
+		return
+	}
+	writeObject(&w, records[0])
+}
+
+func getHistoryEvents(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	username, idUser, err := getIdentity(&w, r)
+	if err != nil {
+		return
+	}
+
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	record, err := dbHandler.GetHistoryRecordByID(id)
+	if err != nil {
+		writeNotFound(&w, "History record not found")
+		return
+	}
+	if !canReadHistoryRecord(dbHandler, username, idUser, &record) {
+		writeForbidden(&w, "Not allowed to read this history record")
+		return
+	}
+
+	evts, err := dbHandler.GetHistoryEvents([]string{id})
+	if err != nil {
+		writeInternalError(&w, "Cannot retrieve history events")
+		return
+	}
+	writeObject(&w, evts)
 }
 
 func deleteHistory(w http.ResponseWriter, r *http.Request) {
@@ -139,5 +226,161 @@ func deleteHistory(w http.ResponseWriter, r *http.Request) {
 		writeInternalError(&w, "Cannot delete history record")
 		return
 	}
+	err := dbHandler.RemoveHistoryEvents(id)
+	if err != nil {
+		writeInternalError(&w, "Cannot delete history events")
+		return
+	}
 	writeObject(&w, "History record deleted successfully")
 }
+
+// subscribeHistory : subscribes a user to the events of a running scan of theirs or of their groups, returns nil if it
+// is already finished
+func subscribeHistory(w *http.ResponseWriter, r *http.Request, id string) (<-chan types.ScanEvent, func(), error) {
+	username, idUser, err := getIdentity(w, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Subscribe before reading the record so no event is missed in between
+	ch, unsubscribe := events.Subscribe(id)
+
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	record, err := dbHandler.GetHistoryRecordByID(id)
+	if err != nil {
+		unsubscribe()
+		writeNotFound(w, "History record not found")
+		return nil, nil, err
+	}
+	if !canReadHistoryRecord(dbHandler, username, idUser, &record) {
+		unsubscribe()
+		writeForbidden(w, "Not allowed to read this history record")
+		return nil, nil, errors.New("history record not readable by user")
+	}
+	if record.IsFinished {
+		unsubscribe()
+		return nil, nil, nil
+	}
+	return ch, unsubscribe, nil
+}
+
+// streamHistory : streams the events of a scan using Server-Sent Events
+func streamHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeInternalError(&w, "Streaming unsupported")
+		return
+	}
+
+	ch, unsubscribe, err := subscribeHistory(&w, r, id)
+	if err != nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	send := func(e types.ScanEvent) {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Kind, data)
+		flusher.Flush()
+	}
+
+	if ch == nil {
+		send(types.ScanEvent{HistoryID: id, Kind: events.KindScanFinished, Stage: events.StageScan})
+		return
+	}
+	defer unsubscribe()
+
+	ticker := time.NewTicker(streamKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case e, ok := <-ch:
+			if !ok {
+				send(types.ScanEvent{HistoryID: id, Kind: events.KindScanFinished, Stage: events.StageScan})
+				return
+			}
+			send(e)
+			if e.Kind == events.KindScanFinished {
+				return
+			}
+		}
+	}
+}
+
+// streamHistoryWS : streams the events of a scan over a WebSocket
+func streamHistoryWS(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	ch, unsubscribe, err := subscribeHistory(&w, r, id)
+	if err != nil {
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		if unsubscribe != nil {
+			unsubscribe()
+		}
+		return
+	}
+	defer conn.Close()
+
+	if ch == nil {
+		conn.WriteJSON(types.ScanEvent{HistoryID: id, Kind: events.KindScanFinished, Stage: events.StageScan})
+		return
+	}
+	defer unsubscribe()
+
+	// Incoming messages are ignored, reading is only needed to notice the client leaving
+	closed := make(chan bool)
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(streamKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			if conn.WriteMessage(websocket.PingMessage, nil) != nil {
+				return
+			}
+		case e, ok := <-ch:
+			if !ok {
+				e = types.ScanEvent{HistoryID: id, Kind: events.KindScanFinished, Stage: events.StageScan}
+			}
+			if conn.WriteJSON(e) != nil {
+				return
+			}
+			if e.Kind == events.KindScanFinished {
+				conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"FaRyuk/config"
+	"FaRyuk/internal/db"
+	"FaRyuk/internal/job"
+	"FaRyuk/internal/types"
+
+	"github.com/gorilla/mux"
+)
+
+var jobQueue *job.Queue
+
+func initJobQueue() {
+	jobQueue = job.NewQueue(config.Cfg.Jobs.Workers,
+		config.Cfg.Jobs.QueueSize,
+		time.Duration(config.Cfg.Jobs.Timeout)*time.Second)
+	jobQueue.Handle(job.KindScan, runScanJob)
+	jobQueue.Handle(job.KindWebScan, runWebScanJob)
+	jobQueue.Handle(job.KindPortScan, runPortScanJob)
+	jobQueue.Handle(job.KindDomainScan, runDomainScanJob)
+	jobQueue.Handle(job.KindSweep, runSweepJob)
+	jobQueue.Handle(job.KindImport, runImportJob)
+
+	err := jobQueue.Start()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func addJobEndpoints(secure *mux.Router) {
+	secure.HandleFunc("/api/jobs", getJobs).Methods("GET")
+	secure.HandleFunc("/api/jobs/{id}", getJobByID).Methods("GET")
+	secure.HandleFunc("/api/jobs/{id}", cancelJob).Methods("DELETE")
+}
+
+func getJobs(w http.ResponseWriter, r *http.Request) {
+	var jobs []types.Job
+
+	username, idUser, err := getIdentity(&w, r)
+	if err != nil {
+		return
+	}
+
+	state := r.URL.Query().Get("state")
+
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	if username == adminUsername {
+		jobs, err = dbHandler.GetJobs(state)
+	} else {
+		jobs, err = dbHandler.GetJobsByOwner(idUser, state)
+	}
+	if err != nil {
+		writeInternalError(&w, dbError)
+		return
+	}
+	writeObject(&w, jobs)
+}
+
+// getOwnedJob : retrieves a job and checks the user is allowed to access it
+func getOwnedJob(w *http.ResponseWriter, r *http.Request) (*types.Job, error) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	username, idUser, err := getIdentity(w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	j, err := dbHandler.GetJobByID(id)
+	if err != nil {
+		writeNotFound(w, "Job not found")
+		return nil, err
+	}
+	if username != adminUsername && j.Owner != idUser {
+		writeForbidden(w, "Authorization error")
+		return nil, errors.New("job not owned by user")
+	}
+	return &j, nil
+}
+
+func getJobByID(w http.ResponseWriter, r *http.Request) {
+	j, err := getOwnedJob(&w, r)
+	if err != nil {
+		return
+	}
+	writeObject(&w, *j)
+}
+
+func cancelJob(w http.ResponseWriter, r *http.Request) {
+	j, err := getOwnedJob(&w, r)
+	if err != nil {
+		return
+	}
+
+	err = jobQueue.Cancel(j.ID)
+	if errors.Is(err, job.ErrFinished) {
+		writeConflict(&w, "Job is already finished")
+		return
+	}
+	if err != nil {
+		writeInternalError(&w, dbError)
+		return
+	}
+	writeObject(&w, "Job cancelled")
+}
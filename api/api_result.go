@@ -9,6 +9,7 @@ import (
 	"FaRyuk/internal/db"
 	"FaRyuk/internal/group"
 	"FaRyuk/internal/helper"
+	"FaRyuk/internal/screenshot"
 	"FaRyuk/internal/types"
 
 	"github.com/gorilla/mux"
@@ -155,6 +156,9 @@ func deleteResultByID(w http.ResponseWriter, r *http.Request) {
 		writeInternalError(&w, dbError)
 		return
 	}
+	for _, wr := range result.WebResults {
+		screenshot.Remove(wr.Screen.ID)
+	}
 
 	writeObject(&w, "Deleted successfully")
 }
@@ -2,25 +2,45 @@ package api
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"html"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"FaRyuk/config"
 	"FaRyuk/internal/db"
 	"FaRyuk/internal/helper"
+	"FaRyuk/internal/job"
 	"FaRyuk/internal/operations"
+	"FaRyuk/internal/screenshot"
+	"FaRyuk/internal/types"
+	"FaRyuk/pkg"
 
 	"github.com/gorilla/mux"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// maxBusterThreads : upper bound of the concurrent requests of a webscan
+	maxBusterThreads = 100
+	// maxBusterDepth : upper bound of the recursion of a webscan
+	maxBusterDepth = 5
+	// maxDNSThreads : upper bound of the concurrent lookups of a domain scan
+	maxDNSThreads = 200
 )
 
 func addScanEndpoints(secure *mux.Router) {
 	secure.HandleFunc("/api/scan", doScan).Methods("POST")
 	secure.HandleFunc("/api/scan-multiple", doMultipleScan).Methods("POST")
 	secure.HandleFunc("/api/domain-scan", doDomainScan).Methods("POST")
+	secure.HandleFunc("/api/resolvers", getResolvers).Methods("GET")
 	secure.HandleFunc("/api/webscan", webscanResultByID).Methods("POST")
 	secure.HandleFunc("/api/portscan", doPortScan).Methods("POST")
 }
@@ -99,27 +119,208 @@ func webscanResultByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	timeout, err := getTimeout(objmap)
+	if err != nil {
+		writeInternalError(&w, "Please provide a valid timeout")
+		return
+	}
+
+	threads, err := getOptionalInt(objmap, "threads", config.Cfg.Buster.Threads)
+	if err != nil || threads <= 0 || threads > maxBusterThreads {
+		writeInternalError(&w, fmt.Sprintf("Please provide a valid threads (1-%d)", maxBusterThreads))
+		return
+	}
+
+	rate, err := getOptionalInt(objmap, "rate", config.Cfg.Buster.Rate)
+	if err != nil || rate < 0 {
+		writeInternalError(&w, "Please provide a valid rate")
+		return
+	}
+
+	depth, err := getOptionalInt(objmap, "depth", 0)
+	if err != nil || depth < 0 || depth > maxBusterDepth {
+		writeInternalError(&w, fmt.Sprintf("Please provide a valid depth (0-%d)", maxBusterDepth))
+		return
+	}
+
+	var extensions string
+	if objmap["extensions"] != nil && unmarshal(objmap["extensions"], &extensions, "Please provide valid extensions") != nil {
+		return
+	}
+
+	var backups bool
+	if objmap["backups"] != nil && unmarshal(objmap["backups"], &backups, "Please provide a valid backups option") != nil {
+		return
+	}
+
 	_, idUser, err := getIdentity(&w, r)
 	if err != nil {
 		writeInternalError(&w, "Identity error")
 		return
 	}
 
-	go operations.WebScanPort(idUser,
-		id,
-		webPort,
-		ssl,
-		base,
-		wordlist,
-		statusCodes,
-		wildcardForced,
-		excludedText,
-		scanners)
+	params := types.JobParams{
+		ResultID:       id,
+		Port:           webPort,
+		Ssl:            ssl,
+		Base:           base,
+		Dirlist:        wordlist,
+		StatusCodes:    statusCodes,
+		WildcardForced: wildcardForced,
+		ExcludedText:   excludedText,
+		Scanners:       scanners,
+		Timeout:        timeout,
+		BusterParams: types.BusterParams{
+			Threads:    threads,
+			Rate:       rate,
+			Extensions: pkg.ParseExtensions(extensions),
+			Depth:      depth,
+			Backups:    backups,
+		},
+	}
+	if submitJob(&w, job.KindWebScan, idUser, "", params) != nil {
+		return
+	}
 
 	writeObject(&w, "Webscan started")
 }
 
-func scanAndSave(idUser string, host string, groupID string, portlist string, dirlist string, rescan bool, scanners []string) {
+// getTimeout : reads the optional per-scan deadline in seconds, 0 means no deadline
+func getTimeout(objmap map[string]json.RawMessage) (int, error) {
+	var timeout int
+	if objmap["timeout"] == nil {
+		return 0, nil
+	}
+	err := json.Unmarshal(objmap["timeout"], &timeout)
+	if err != nil {
+		return 0, err
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("negative timeout")
+	}
+	return timeout, nil
+}
+
+// getOptionalInt : reads an optional integer field, def is returned when it is missing
+func getOptionalInt(objmap map[string]json.RawMessage, key string, def int) (int, error) {
+	var value int
+	if objmap[key] == nil {
+		return def, nil
+	}
+	err := json.Unmarshal(objmap[key], &value)
+	if err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+func submitJob(w *http.ResponseWriter, kind string, idUser string, groupID string, params types.JobParams) error {
+	err := jobQueue.Submit(job.NewJob(kind, idUser, groupID, params))
+	if err != nil {
+		writeInternalError(w, fmt.Sprintf("Could not queue scan : %s", err))
+	}
+	return err
+}
+
+func runWebScanJob(ctx context.Context, j *types.Job) error {
+	p := j.Params
+	return operations.WebScanPort(ctx, j.Owner,
+		p.ResultID,
+		p.Port,
+		p.Ssl,
+		p.Base,
+		p.Dirlist,
+		p.StatusCodes,
+		p.WildcardForced,
+		p.ExcludedText,
+		p.BusterParams,
+		p.Scanners)
+}
+
+func runScanJob(ctx context.Context, j *types.Job) error {
+	p := j.Params
+	err := scanAndSave(ctx, j.Owner, p.Host, j.OwnerGroup, p.Portlist, p.Dirlist, p.Rescan, p.Scanners)
+	if err != nil {
+		return err
+	}
+	if len(p.Tags) != 0 {
+		err = tagHost(j.Owner, p.Host, p.Tags)
+		if err != nil {
+			return err
+		}
+	}
+	if !p.FollowSANs || ctx.Err() != nil {
+		return nil
+	}
+	return scanCandidates(j.ID, j.Owner, j.OwnerGroup, p)
+}
+
+// tagHost : adds tags to the results of a host
+func tagHost(idUser string, host string, tags []string) error {
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	rs, err := dbHandler.GetResultsByHostAndOwner(host, idUser)
+	if err != nil {
+		return err
+	}
+	for _, r := range rs {
+		err = dbHandler.AddTagsToResult(r.ID, tags)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanCandidates : queues scans of the hosts found in the certificates of a scanned host that have no result yet, only
+// the ones under the same registrable domain are followed so that shared certificates do not reach third parties
+func scanCandidates(parentID string, idUser string, groupID string, params types.JobParams) error {
+	// IP addresses and public suffixes have no registrable domain to stay in
+	if net.ParseIP(params.Host) != nil {
+		return nil
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(params.Host))
+	if err != nil {
+		return nil
+	}
+
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	rs, err := dbHandler.GetResultsByHostAndOwner(params.Host, idUser)
+	if err != nil || len(rs) == 0 {
+		return err
+	}
+
+	hosts := make([]string, 0)
+	for _, candidate := range rs[0].Candidates {
+		if candidate != domain && !strings.HasSuffix(candidate, "."+domain) {
+			continue
+		}
+		known, err := dbHandler.GetResultsByHostAndOwner(candidate, idUser)
+		if err == nil && len(known) == 0 {
+			hosts = append(hosts, candidate)
+		}
+	}
+
+	// Only the host scanned first follows its certificates and known hosts are never rescanned, so that
+	// certificates pointing at each other do not loop
+	params.FollowSANs = false
+	params.Rescan = false
+	return jobQueue.SubmitAll(newJobs(job.KindScan, parentID, idUser, groupID, hosts, params))
+}
+
+func runPortScanJob(ctx context.Context, j *types.Job) error {
+	p := j.Params
+	return operations.RunnerScanPort(ctx, j.Owner, p.ResultID, p.Port, p.Scanners)
+}
+
+func runDomainScanJob(ctx context.Context, j *types.Job) error {
+	return scanDomainAndSave(ctx, j.ID, j.Owner, j.OwnerGroup, j.Params)
+}
+
+func scanAndSave(ctx context.Context, idUser string, host string, groupID string, portlist string, dirlist string, rescan bool, scanners []string) error {
 
 	dbHandler := db.NewDBHandler()
 	defer dbHandler.CloseConnection()
@@ -128,13 +329,11 @@ func scanAndSave(idUser string, host string, groupID string, portlist string, di
 	l := sync.Mutex{}
 	if len(rs) > 0 {
 		if !rescan {
-			backgroundScans--
-			return
+			return nil
 		}
-		result, err := operations.DoHost(idUser, host, groupID, portlist, dirlist, scanners)
+		result, err := operations.DoHost(ctx, idUser, host, groupID, portlist, dirlist, scanners)
 		if err != nil {
-			backgroundScans--
-			return
+			return err
 		}
 		l.Lock()
 		orig := dbHandler.GetResultByID(rs[0].ID)
@@ -158,7 +357,15 @@ func scanAndSave(idUser string, host string, groupID string, portlist string, di
 			}
 
 			// Merge web results
+			if orig.WebResults[idxOrig].Screen.ID != wr.Screen.ID {
+				screenshot.Remove(orig.WebResults[idxOrig].Screen.ID)
+			}
 			orig.WebResults[idxOrig].Screen = wr.Screen
+			orig.WebResults[idxOrig].Baseline = wr.Baseline
+			orig.WebResults[idxOrig].Headers = wr.Headers
+			orig.WebResults[idxOrig].Audit = wr.Audit
+			orig.WebResults[idxOrig].TLS = wr.TLS
+			orig.WebResults[idxOrig].Technologies = wr.Technologies
 			for _, busterRes := range wr.Busterres {
 				exists = false
 				// Check if dir is already found
@@ -180,29 +387,65 @@ func scanAndSave(idUser string, host string, groupID string, portlist string, di
 					orig.OpenPorts = append(orig.OpenPorts, port)
 				}
 			}
+			for _, tag := range result.Tags {
+				if !helper.ContainsStr(orig.Tags, tag) {
+					orig.Tags = append(orig.Tags, tag)
+				}
+			}
+			for _, candidate := range result.Candidates {
+				if !helper.ContainsStr(orig.Candidates, candidate) {
+					orig.Candidates = append(orig.Candidates, candidate)
+				}
+			}
+			for _, service := range result.Services {
+				exists := false
+				for idx := range orig.Services {
+					if orig.Services[idx].Port == service.Port {
+						orig.Services[idx] = service
+						exists = true
+					}
+				}
+				if !exists {
+					orig.Services = append(orig.Services, service)
+				}
+			}
+			for _, udpPort := range result.OpenUDPPorts {
+				exists := false
+				for idx := range orig.OpenUDPPorts {
+					if orig.OpenUDPPorts[idx].Port == udpPort.Port {
+						orig.OpenUDPPorts[idx].State = udpPort.State
+						exists = true
+					}
+				}
+				if !exists {
+					orig.OpenUDPPorts = append(orig.OpenUDPPorts, udpPort)
+				}
+			}
 		}
 
+		orig.Security = operations.SecuritySummary(orig.WebResults)
 		if !helper.ContainsStr(orig.Tags, "#new") {
 			orig.Tags = append(orig.Tags, "#new")
 		}
 		orig.OwnerGroup = groupID
-		dbHandler.UpdateResult(orig)
+		ok := dbHandler.UpdateResult(orig)
 		l.Unlock()
+		if !ok {
+			return fmt.Errorf("could not update result")
+		}
 	} else {
-		result, err := operations.DoHost(idUser, host, groupID, portlist, dirlist, scanners)
+		result, err := operations.DoHost(ctx, idUser, host, groupID, portlist, dirlist, scanners)
 		if err != nil {
-			fmt.Println(err)
-			return
+			return err
 		}
 		l.Lock()
 		err = dbHandler.InsertResult(&result)
 		l.Unlock()
 		if err != nil {
-			backgroundScans--
-			return
+			return err
 		}
 	}
-	backgroundScans--
+	return nil
 }
 
 func doScan(w http.ResponseWriter, r *http.Request) {
@@ -261,13 +504,56 @@ func doScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	timeout, err := getTimeout(objmap)
+	if err != nil {
+		writeInternalError(&w, "Please provide a valid timeout")
+		return
+	}
+
+	var followSANs bool
+	err = json.Unmarshal(objmap["followSans"], &followSANs)
+	if err != nil && objmap["followSans"] != nil {
+		writeInternalError(&w, "Please provide a valid followSans option")
+		return
+	}
+
 	_, idUser, err := getIdentity(&w, r)
 	if err != nil {
 		return
 	}
 
-	backgroundScans++
-	go scanAndSave(idUser, html.EscapeString(host), groupID, portlistFilename, dirlistFilename, rescan, scanners)
+	var discover bool
+	err = json.Unmarshal(objmap["discover"], &discover)
+	if err != nil && objmap["discover"] != nil {
+		writeInternalError(&w, "Please provide a valid discover option")
+		return
+	}
+
+	hosts, networks, err := splitTargets([]string{host})
+	if err != nil {
+		writeInternalError(&w, fmt.Sprintf("Please provide a valid host : %s", err))
+		return
+	}
+
+	params := types.JobParams{
+		Portlist:   portlistFilename,
+		Dirlist:    dirlistFilename,
+		Rescan:     rescan,
+		Scanners:   scanners,
+		Timeout:    timeout,
+		FollowSANs: followSANs,
+		Discover:   discover,
+	}
+	kind := job.KindScan
+	if len(networks) > 0 {
+		kind = job.KindSweep
+		params.Host = networks[0]
+	} else {
+		params.Host = hosts[0]
+	}
+	if submitJob(&w, kind, idUser, groupID, params) != nil {
+		return
+	}
 	writeObject(&w, "Scan started")
 }
 
@@ -294,10 +580,12 @@ func doMultipleScan(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	hosts := make([]string, 0)
+	targets := make([]string, 0)
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		hosts = append(hosts, html.EscapeString(scanner.Text()))
+		if strings.TrimSpace(scanner.Text()) != "" {
+			targets = append(targets, scanner.Text())
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -305,6 +593,12 @@ func doMultipleScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	hosts, networks, err := splitTargets(targets)
+	if err != nil {
+		writeInternalError(&w, fmt.Sprintf("Please provide valid hosts : %s", err))
+		return
+	}
+
 	groupID := r.PostForm["idGroup"][0]
 	rescan := true
 	if len(r.PostForm["rescan"]) == 0 {
@@ -313,39 +607,142 @@ func doMultipleScan(w http.ResponseWriter, r *http.Request) {
 	dirlistFilename := r.PostForm["dirlist"][0]
 	portlistFilename := r.PostForm["portlist"][0]
 	scanners := r.PostForm["scanners"]
-	go scanMultipleAndSave(idUser, hosts, groupID, portlistFilename, dirlistFilename, rescan, scanners)
+	timeout := 0
+	if len(r.PostForm["timeout"]) != 0 {
+		timeout, err = strconv.Atoi(r.PostForm["timeout"][0])
+		if err != nil || timeout < 0 {
+			writeInternalError(&w, "Please provide a valid timeout")
+			return
+		}
+	}
+	params := types.JobParams{
+		Portlist: portlistFilename,
+		Dirlist:  dirlistFilename,
+		Rescan:   rescan,
+		Scanners: scanners,
+		Timeout:  timeout,
+		Discover: len(r.PostForm["discover"]) != 0,
+	}
+	jobs := append(newJobs(job.KindScan, "", idUser, groupID, hosts, params),
+		newJobs(job.KindSweep, "", idUser, groupID, networks, params)...)
+	err = jobQueue.SubmitAll(jobs)
+	if err != nil {
+		writeInternalError(&w, fmt.Sprintf("Could not queue scan : %s", err))
+		return
+	}
 	writeObject(&w, "Scan multiple started")
 }
 
-func scanMultipleAndSave(idUser string, hosts []string,
-	groupID string,
-	portlist string, dirlist string,
-	rescan bool, scanners []string) {
-	backgroundScans += len(hosts)
-	sem := make(chan bool, 5)
-	for _, host := range hosts {
-		sem <- true
-		go func(host string) {
-			scanAndSave(idUser, host, groupID, portlist, dirlist, rescan, scanners)
-			<-sem
-		}(host)
-	}
-	for i := 0; i < cap(sem); i++ {
-		sem <- true
+// scanMultipleAndSave : queues one scan job per host using params as a template, none is queued when the queue cannot
+// take them all
+func scanMultipleAndSave(idUser string, hosts []string, groupID string, params types.JobParams) error {
+	return jobQueue.SubmitAll(newJobs(job.KindScan, "", idUser, groupID, hosts, params))
+}
+
+// newJobs : returns one job per target using params as a template, parentID is the job queuing them if any
+func newJobs(kind string, parentID string, idUser string, groupID string, targets []string, params types.JobParams) []*types.Job {
+	jobs := make([]*types.Job, 0, len(targets))
+	for _, target := range targets {
+		params.Host = target
+		j := job.NewJob(kind, idUser, groupID, params)
+		j.ParentID = parentID
+		jobs = append(jobs, j)
+	}
+	return jobs
+}
+
+// splitTargets : separates host names and IP addresses from networks and ranges,
+// it fails if a target is invalid or if they expand to more addresses than allowed
+func splitTargets(targets []string) ([]string, []string, error) {
+	hosts := make([]string, 0)
+	networks := make([]string, 0)
+	size := 0
+	for _, target := range targets {
+		target = strings.TrimSpace(target)
+		addresses, err := pkg.ExpandTarget(target, config.Cfg.Targets.MaxSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		size += len(addresses)
+		if size > config.Cfg.Targets.MaxSize {
+			return nil, nil, fmt.Errorf("targets have more than %d addresses", config.Cfg.Targets.MaxSize)
+		}
+		if pkg.IsNetworkTarget(target) {
+			networks = append(networks, target)
+		} else {
+			hosts = append(hosts, html.EscapeString(addresses[0]))
+		}
+	}
+	return hosts, networks, nil
+}
+
+// runSweepJob : expands a network, optionally keeps its live addresses, and queues a scan of each of them
+func runSweepJob(ctx context.Context, j *types.Job) error {
+	p := j.Params
+	hosts, err := pkg.ExpandTarget(p.Host, config.Cfg.Targets.MaxSize)
+	if err != nil {
+		return err
 	}
+	if p.Discover {
+		hosts = pkg.NewHostDiscovery(time.Second, 50).Run(ctx, hosts)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	p.Discover = false
+	return jobQueue.SubmitAll(newJobs(job.KindScan, j.ID, j.Owner, j.OwnerGroup, hosts, p))
 }
 
-func scanDomainAndSave(idUser string, domain string,
-	groupID string,
-	dnslistFilename string, portlistFilename string,
-	dirlistFilename string, resolver string,
-	isWildcard bool, rescan bool, scanners []string) {
+// scanDomainAndSave : scans the subdomains of a domain and queues a scan of each of them, parentID is the job of the
+// domain scan
+func scanDomainAndSave(ctx context.Context, parentID string, idUser string, groupID string, params types.JobParams) error {
+	domain := params.Domain
+	resolvers := params.Resolvers
+	if params.Resolver != "" {
+		resolvers = append([]string{params.Resolver}, resolvers...)
+	}
+	hosts, err := operations.DoDomain(
+		ctx, idUser, domain, groupID, params.Dnslist, params.WildcardForced, resolvers,
+		params.Threads, params.Rate, params.Resume, params.Permutations,
+	)
+	if err != nil {
+		return err
+	}
 
-	hosts, _ := operations.DoDomain(idUser, domain, groupID, dnslistFilename, isWildcard, resolver)
+	// Hosts which can be taken over are tagged once scanned
+	takeovers := make(map[string]bool)
+	dbHandler := db.NewDBHandler()
+	if domainResult := dbHandler.GetDomainResultByDomainAndOwner(domain, idUser); domainResult != nil {
+		for _, f := range domainResult.Findings {
+			if f.Kind == types.FindingTakeover {
+				takeovers[f.Subdomain] = true
+			}
+		}
+	}
+	dbHandler.CloseConnection()
+
+	vulnerable := make([]string, 0)
+	others := make([]string, 0)
 	for idx := range hosts {
-		hosts[idx] = hosts[idx] + "." + domain
+		host := hosts[idx] + "." + domain
+		if takeovers[host] {
+			vulnerable = append(vulnerable, host)
+		} else {
+			others = append(others, host)
+		}
 	}
-	go scanMultipleAndSave(idUser, hosts, groupID, portlistFilename, dirlistFilename, rescan, scanners)
+
+	hostParams := types.JobParams{
+		Portlist: params.Portlist,
+		Dirlist:  params.Dirlist,
+		Rescan:   params.Rescan,
+		Scanners: params.Scanners,
+		Timeout:  params.Timeout,
+	}
+	jobs := newJobs(job.KindScan, parentID, idUser, groupID, others, hostParams)
+	hostParams.Tags = []string{"#takeover"}
+	jobs = append(jobs, newJobs(job.KindScan, parentID, idUser, groupID, vulnerable, hostParams)...)
+	return jobQueue.SubmitAll(jobs)
 }
 
 func doPortScan(w http.ResponseWriter, r *http.Request) {
@@ -392,16 +789,27 @@ func doPortScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	timeout, err := getTimeout(objmap)
+	if err != nil {
+		writeInternalError(&w, "Please provide a valid timeout")
+		return
+	}
+
 	_, idUser, err := getIdentity(&w, r)
 	if err != nil {
 		writeInternalError(&w, "Identity error")
 		return
 	}
 
-	go operations.RunnerScanPort(idUser,
-		id,
-		port,
-		scanners)
+	params := types.JobParams{
+		ResultID: id,
+		Port:     port,
+		Scanners: scanners,
+		Timeout:  timeout,
+	}
+	if submitJob(&w, job.KindPortScan, idUser, "", params) != nil {
+		return
+	}
 
 	writeObject(&w, "port runner scan started")
 }
@@ -467,6 +875,11 @@ func doDomainScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var resolvers []string
+	if objmap["resolvers"] != nil && unmarshal(objmap["resolvers"], &resolvers, "Please provide valid resolvers") != nil {
+		return
+	}
+
 	var rescan bool
 	if unmarshal(objmap["rescan"], &rescan, "Please provide a valid rescan") != nil {
 		return
@@ -482,14 +895,64 @@ func doDomainScan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go scanDomainAndSave(idUser, domain, groupID,
-		dnslistFilename, portlistFilename,
-		dirlistFilename, resolver,
-		wildcard, rescan, scanners)
+	timeout, err := getTimeout(objmap)
+	if err != nil {
+		writeInternalError(&w, "Please provide a valid timeout")
+		return
+	}
+
+	threads, err := getOptionalInt(objmap, "threads", config.Cfg.DNS.Threads)
+	if err != nil || threads <= 0 || threads > maxDNSThreads {
+		writeInternalError(&w, fmt.Sprintf("Please provide a valid threads (1-%d)", maxDNSThreads))
+		return
+	}
+
+	rate, err := getOptionalInt(objmap, "rate", config.Cfg.DNS.Rate)
+	if err != nil || rate < 0 {
+		writeInternalError(&w, "Please provide a valid rate")
+		return
+	}
+
+	var resume bool
+	if objmap["resume"] != nil && unmarshal(objmap["resume"], &resume, "Please provide a valid resume option") != nil {
+		return
+	}
+
+	permutations, err := getOptionalInt(objmap, "permutations", 0)
+	if err != nil || permutations < 0 || permutations > config.Cfg.DNS.MaxPermutations {
+		writeInternalError(&w, fmt.Sprintf("Please provide a valid permutations (0-%d)", config.Cfg.DNS.MaxPermutations))
+		return
+	}
+
+	params := types.JobParams{
+		Domain:         domain,
+		Dnslist:        dnslistFilename,
+		Portlist:       portlistFilename,
+		Dirlist:        dirlistFilename,
+		Resolver:       resolver,
+		Resolvers:      resolvers,
+		WildcardForced: wildcard,
+		Rescan:         rescan,
+		Scanners:       scanners,
+		Timeout:        timeout,
+		Resume:         resume,
+		Permutations:   permutations,
+		BusterParams: types.BusterParams{
+			Threads: threads,
+			Rate:    rate,
+		},
+	}
+	if submitJob(&w, job.KindDomainScan, idUser, groupID, params) != nil {
+		return
+	}
 
 	writeObject(&w, "Scan domain started")
 }
 
+func getResolvers(w http.ResponseWriter, r *http.Request) {
+	writeObject(&w, pkg.DefaultResolverPool().Stats())
+}
+
 func getDnsLists(w http.ResponseWriter, r *http.Request) {
 	var dnsList = helper.GetDNSlists()
 	writeObject(&w, dnsList)
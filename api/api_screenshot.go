@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"FaRyuk/internal/blob"
+	"FaRyuk/internal/db"
+	"FaRyuk/internal/group"
+	"FaRyuk/internal/helper"
+	"FaRyuk/internal/screenshot"
+	"FaRyuk/internal/types"
+	"FaRyuk/pkg"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultClusterDistance : largest Hamming distance between the hashes of similar screenshots
+const defaultClusterDistance = 8
+
+func addScreenshotEndpoints(secure *mux.Router) {
+	secure.HandleFunc("/api/screenshot/{id}", getScreenshot).Methods("GET")
+	secure.HandleFunc("/api/screenshot-clusters", getScreenshotClusters).Methods("GET")
+	secure.HandleFunc("/api/screenshot-clusters/tag", tagScreenshotCluster).Methods("POST")
+}
+
+// canReadResult : tells whether a user owns a result, is shared it or belongs to its group
+func canReadResult(dbHandler *db.Handler, username, idUser string, result *types.Result) bool {
+	if username == adminUsername || result.Owner == idUser || helper.ContainsStr(result.SharedWith, idUser) {
+		return true
+	}
+	user := dbHandler.GetUserByID(idUser)
+	return user != nil && helper.ContainsStr(group.ToIDsArray(user.Groups), result.OwnerGroup)
+}
+
+func getScreenshot(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	thumbnail := r.URL.Query().Get("thumbnail") == "true"
+
+	username, idUser, err := getIdentity(&w, r)
+	if err != nil {
+		return
+	}
+
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	result := dbHandler.GetResultByScreenshot(id)
+	if result == nil {
+		writeNotFound(&w, "Screenshot not found")
+		return
+	}
+	if !canReadResult(dbHandler, username, idUser, result) {
+		writeForbidden(&w, "Forbidden")
+		return
+	}
+
+	image, err := screenshot.Get(id, thumbnail)
+	if errors.Is(err, blob.ErrNotFound) {
+		writeNotFound(&w, "Screenshot not found")
+		return
+	}
+	if err != nil {
+		writeInternalError(&w, "Could not read screenshot")
+		return
+	}
+
+	w.Header().Set("Content-Type", http.DetectContentType(image))
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Write(image)
+}
+
+// getReadableResults : returns the results matching a search that a user can read
+func getReadableResults(dbHandler *db.Handler, username, idUser string, searchMap map[string]string) ([]types.Result, error) {
+	if username == adminUsername {
+		return dbHandler.GetResultsBySearch(searchMap, -1, -1)
+	}
+	user := dbHandler.GetUserByID(idUser)
+	return dbHandler.GetResultsBySearchAndOwner(searchMap, idUser, group.ToIDsArray(user.Groups), -1, -1)
+}
+
+// screenshotMembers : returns the hashed screenshots of the web results of results
+func screenshotMembers(results []types.Result) []types.ScreenshotMember {
+	members := make([]types.ScreenshotMember, 0)
+	for _, result := range results {
+		for _, wr := range result.WebResults {
+			if wr.Screen.Hash == "" {
+				continue
+			}
+			members = append(members, types.ScreenshotMember{
+				ResultID:     result.ID,
+				Host:         result.Host,
+				Port:         wr.Port,
+				ScreenshotID: wr.Screen.ID,
+				Hash:         wr.Screen.Hash,
+			})
+		}
+	}
+	return members
+}
+
+func getScreenshotClusters(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	searchMap := helper.Tokenize(query.Get("search") + " ")
+	distance := defaultClusterDistance
+	if d, err := strconv.Atoi(query.Get("distance")); err == nil && d >= 0 {
+		distance = d
+	}
+	minSize := 2
+	if m, err := strconv.Atoi(query.Get("min")); err == nil && m > 0 {
+		minSize = m
+	}
+
+	username, idUser, err := getIdentity(&w, r)
+	if err != nil {
+		return
+	}
+
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	results, err := getReadableResults(dbHandler, username, idUser, searchMap)
+	if err != nil {
+		writeInternalError(&w, dbError)
+		return
+	}
+
+	members := screenshotMembers(results)
+	hashes := make([]string, len(members))
+	for idx, m := range members {
+		hashes[idx] = m.Hash
+	}
+
+	clusters := make([]types.ScreenshotCluster, 0)
+	for _, indexes := range pkg.ClusterHashes(hashes, distance) {
+		if len(indexes) < minSize {
+			break
+		}
+		cluster := types.ScreenshotCluster{Hash: hashes[indexes[0]], Size: len(indexes)}
+		for _, idx := range indexes {
+			cluster.Members = append(cluster.Members, members[idx])
+		}
+		clusters = append(clusters, cluster)
+	}
+	writeObject(&w, clusters)
+}
+
+func tagScreenshotCluster(w http.ResponseWriter, r *http.Request) {
+	var objmap map[string]json.RawMessage
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeInternalError(&w, "Unexpected error")
+		return
+	}
+
+	err = json.Unmarshal(body, &objmap)
+	if err != nil {
+		writeInternalError(&w, "Please provide a valid json")
+		return
+	}
+
+	var hash string
+	err = json.Unmarshal(objmap["hash"], &hash)
+	if err != nil || hash == "" {
+		writeInternalError(&w, "Please provide a 'hash'")
+		return
+	}
+
+	var content string
+	err = json.Unmarshal(objmap["tags"], &content)
+	tags := helper.GetTags(strings.ToLower(content))
+	if err != nil || len(tags) == 0 {
+		writeInternalError(&w, "Please provide 'tags'")
+		return
+	}
+
+	distance, err := getOptionalInt(objmap, "distance", defaultClusterDistance)
+	if err != nil || distance < 0 {
+		writeInternalError(&w, "Please provide a valid 'distance'")
+		return
+	}
+	var search string
+	json.Unmarshal(objmap["search"], &search)
+
+	username, idUser, err := getIdentity(&w, r)
+	if err != nil {
+		return
+	}
+
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	results, err := getReadableResults(dbHandler, username, idUser, helper.Tokenize(search+" "))
+	if err != nil {
+		writeInternalError(&w, dbError)
+		return
+	}
+
+	tagged := make([]string, 0)
+	for _, m := range screenshotMembers(results) {
+		d, err := pkg.HashDistance(hash, m.Hash)
+		if err != nil || d > distance || helper.ContainsStr(tagged, m.ResultID) {
+			continue
+		}
+		err = dbHandler.AddTagsToResult(m.ResultID, tags)
+		if err != nil {
+			writeInternalError(&w, dbError)
+			return
+		}
+		tagged = append(tagged, m.ResultID)
+	}
+	writeObject(&w, tagged)
+}
@@ -8,8 +8,10 @@ import (
 	"time"
 
 	"FaRyuk/config"
+	"FaRyuk/internal/db"
 	"FaRyuk/internal/types"
 	"FaRyuk/internal/user"
+	"FaRyuk/pkg"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -21,7 +23,6 @@ const (
 	unexpectedError = "Unexpected error"
 )
 
-var backgroundScans = 0
 var startTime time.Time
 
 func initKeys() {
@@ -32,6 +33,40 @@ func initKeys() {
 	fmt.Printf("JWT SECRET %s\n", JWTSecret)
 }
 
+func initIndexes() {
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	err := dbHandler.EnsureHistoryEventIndexes()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initTechnologies() {
+	if config.Cfg.Technologies.File == "" {
+		return
+	}
+	if config.Cfg.Technologies.Categories != "" {
+		err := pkg.LoadCategories(config.Cfg.Technologies.Categories)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	err := pkg.LoadTechnologies(config.Cfg.Technologies.File)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initBrowserPool() {
+	pkg.SetBrowserPool(pkg.NewBrowserPool(config.Cfg.Screenshots.Browsers, config.Cfg.Screenshots.Tabs))
+}
+
+func initResolverPool() {
+	pkg.SetResolverPool(pkg.NewResolverPool(config.Cfg.DNS.Resolvers, config.Cfg.DNS.Trusted, 2*time.Second, config.Cfg.DNS.Rate))
+}
+
 func getCookie(name string, r *http.Request) (string, error) {
 	tokenCookie, err := r.Cookie(name)
 	if err != nil {
@@ -74,6 +109,11 @@ func writeNotFound(w *http.ResponseWriter, m string) {
 	writeResponse(w, types.JSONReturn{Status: "Fail", Body: m})
 }
 
+func writeConflict(w *http.ResponseWriter, m string) {
+	(*w).WriteHeader(http.StatusConflict)
+	writeResponse(w, types.JSONReturn{Status: "Fail", Body: m})
+}
+
 func writeInternalError(w *http.ResponseWriter, m string) {
 	(*w).WriteHeader(http.StatusInternalServerError)
 	writeResponse(w, types.JSONReturn{Status: "Fail", Body: m})
@@ -86,6 +126,11 @@ func writeObject(w *http.ResponseWriter, m interface{}) {
 // HandleRequests : set up routes for API
 func HandleRequests() {
 	initKeys()
+	initIndexes()
+	initTechnologies()
+	initBrowserPool()
+	initResolverPool()
+	initJobQueue()
 	startTime = time.Now()
 	myRouter := mux.NewRouter().StrictSlash(true)
 
@@ -116,6 +161,15 @@ func HandleRequests() {
 	// Scans endpoints
 	addScanEndpoints(secure)
 
+	// Jobs endpoints
+	addJobEndpoints(secure)
+
+	// Screenshots endpoints
+	addScreenshotEndpoints(secure)
+
+	// Domains endpoints
+	addDomainEndpoints(secure)
+
 	// Lists helper
 	secure.HandleFunc("/api/get-dnslists", getDnsLists).Methods("GET")
 	secure.HandleFunc("/api/get-wordlists", getWordLists).Methods("GET")
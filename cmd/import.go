@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"FaRyuk/config"
+	"FaRyuk/internal/blob"
+	"FaRyuk/internal/db"
+	"FaRyuk/internal/job"
+	"FaRyuk/internal/operations"
+	"FaRyuk/internal/types"
+	"FaRyuk/pkg"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	importUser     string
+	importGroup    string
+	importFormat   string
+	importResolve  bool
+	importPortlist string
+	importDirlist  string
+)
+
+var importSubdomainsCmd = &cobra.Command{
+	Use:   "import-subdomains DOMAIN FILE",
+	Short: "Import the subdomains of a dataset (list, JSON lines or CSV, - for stdin) in the domain results of a user",
+	Args:  cobra.ExactArgs(2),
+	Run:   LaunchImportSubdomains,
+}
+
+// LaunchImportSubdomains : imports a subdomain dataset and queues scans of the subdomains imported
+func LaunchImportSubdomains(cmd *cobra.Command, args []string) {
+	config.Init()
+	domain, path := strings.ToLower(strings.TrimSuffix(args[0], ".")), args[1]
+	if importPortlist != "" && importDirlist == "" {
+		fmt.Println("A dirlist is needed to scan the subdomains imported")
+		os.Exit(1)
+	}
+	// The server reads the dataset from the blob store, a relative directory is not the one of the server
+	backend := config.Cfg.Blobs.Backend
+	if importResolve && (backend == blob.BackendFilesystem || backend == "") && !filepath.IsAbs(config.Cfg.Blobs.Dir) {
+		fmt.Println("blobs.dir has to be an absolute path shared with the server to import with --resolve")
+		os.Exit(1)
+	}
+
+	dbHandler := db.NewDBHandler()
+	user := dbHandler.GetUserByUsername(importUser)
+	dbHandler.CloseConnection()
+	if user == nil {
+		fmt.Printf("Unknown user %s\n", importUser)
+		os.Exit(1)
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	names, err := pkg.ParseSubdomains(r, importFormat, domain)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	params := types.JobParams{Domain: domain, Portlist: importPortlist, Dirlist: importDirlist}
+
+	// Resolving can take long, the server imports the dataset in a job
+	if importResolve {
+		params.Dataset, err = operations.StoreImportDataset(names)
+		if err == nil {
+			err = job.Enqueue(job.NewJob(job.KindImport, user.ID, importGroup, params))
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("%d subdomains, import queued\n", len(names))
+		return
+	}
+
+	summary, err := operations.ImportSubdomains(context.Background(), user.ID, importGroup, domain, names, false)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d subdomains, %d already found, %d imported\n", summary.Total, summary.Duplicates, len(summary.Imported))
+
+	// The server picks up the jobs
+	if importPortlist == "" {
+		return
+	}
+	params.Domain = ""
+	for _, host := range summary.Imported {
+		params.Host = host
+		err = job.Enqueue(job.NewJob(job.KindScan, user.ID, importGroup, params))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("%d scans queued\n", len(summary.Imported))
+}
+
+func init() {
+	rootCmd.AddCommand(importSubdomainsCmd)
+	importSubdomainsCmd.Flags().StringVarP(&importUser, "user", "u", "", "Username owning the domain results")
+	importSubdomainsCmd.Flags().StringVarP(&importGroup, "group", "g", "", "Group ID of the domain results and scans")
+	importSubdomainsCmd.Flags().StringVarP(&importFormat, "format", "f", "", "Format of the dataset : list, jsonl or csv, guessed when empty")
+	importSubdomainsCmd.Flags().BoolVarP(&importResolve, "resolve", "r", false, "Leave out the subdomains which do not resolve, the server imports the dataset")
+	importSubdomainsCmd.Flags().StringVarP(&importPortlist, "portlist", "p", "", "Port list of the scans of the subdomains imported, none are scanned when empty")
+	importSubdomainsCmd.Flags().StringVarP(&importDirlist, "dirlist", "d", "", "Wordlist of the scans of the subdomains imported, needed with a portlist")
+	importSubdomainsCmd.MarkFlagRequired("user")
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"FaRyuk/config"
+	"FaRyuk/internal/db"
+	"FaRyuk/internal/screenshot"
+
+	"github.com/spf13/cobra"
+)
+
+var migrateScreenshotsCmd = &cobra.Command{
+	Use:   "migrate-screenshots",
+	Short: "Move the screenshots stored in results to the blob store",
+	Run:   LaunchMigrateScreenshots,
+}
+
+// LaunchMigrateScreenshots : moves base64 screenshots out of the result documents
+func LaunchMigrateScreenshots(cmd *cobra.Command, args []string) {
+	config.Init()
+	dbHandler := db.NewDBHandler()
+	migrated, err := screenshot.Migrate(dbHandler)
+	dbHandler.CloseConnection()
+	fmt.Printf("%d screenshots migrated\n", migrated)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(migrateScreenshotsCmd)
+}
@@ -3,9 +3,11 @@ package config
 import (
 	"fmt"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 
-	"github.com/kelseyhightower/envconfig"
 	"gopkg.in/yaml.v2"
 )
 
@@ -18,6 +20,58 @@ type Config struct {
 		URI  string `yaml:"uri" envconfig:"DB_URI" required:"true"`
 		Name string `yaml:"name" envconfig:"DB_NAME" required:"true"`
 	} `yaml:"database"`
+	Jobs struct {
+		Workers   int `yaml:"workers" envconfig:"JOBS_WORKERS" default:"5"`
+		QueueSize int `yaml:"queueSize" envconfig:"JOBS_QUEUE_SIZE" default:"1000"`
+		// Default deadline of a scan in seconds, 0 means no deadline
+		Timeout int `yaml:"timeout" envconfig:"JOBS_TIMEOUT"`
+	} `yaml:"jobs"`
+	Buster struct {
+		Threads int `yaml:"threads" envconfig:"BUSTER_THREADS" default:"10"`
+		// Maximum requests per second sent to a host, 0 means unlimited
+		Rate int `yaml:"rate" envconfig:"BUSTER_RATE"`
+	} `yaml:"buster"`
+	DNS struct {
+		// Concurrent lookups of the subdomain brute force
+		Threads int `yaml:"threads" envconfig:"DNS_THREADS" default:"20"`
+		// Maximum queries per second sent to a resolver, 0 means unlimited
+		Rate int `yaml:"rate" envconfig:"DNS_RATE"`
+		// Maximum number of permutations of the subdomains found resolved by a domain scan asking for them
+		MaxPermutations int `yaml:"maxPermutations" envconfig:"DNS_MAX_PERMUTATIONS" default:"5000"`
+		// Resolvers used in turn by the scans, empty means the system resolver
+		Resolvers []string `yaml:"resolvers" envconfig:"DNS_RESOLVERS"`
+		// Resolver confirming the answers of the others, empty means the system resolver
+		Trusted string `yaml:"trusted" envconfig:"DNS_TRUSTED"`
+	} `yaml:"dns"`
+	Targets struct {
+		// Maximum number of addresses a CIDR network or a range may expand to
+		MaxSize int `yaml:"maxSize" envconfig:"TARGETS_MAX_SIZE" default:"1024"`
+	} `yaml:"targets"`
+	Screenshots struct {
+		// Number of Chrome instances and of tabs each one may open at once
+		Browsers int `yaml:"browsers" envconfig:"SCREENSHOTS_BROWSERS" default:"2"`
+		Tabs     int `yaml:"tabs" envconfig:"SCREENSHOTS_TABS" default:"4"`
+		// Timeout of a screenshot and longest wait for the network to be idle, in seconds
+		Timeout  int  `yaml:"timeout" envconfig:"SCREENSHOTS_TIMEOUT" default:"30"`
+		IdleWait int  `yaml:"idleWait" envconfig:"SCREENSHOTS_IDLE_WAIT" default:"5"`
+		Width    int  `yaml:"width" envconfig:"SCREENSHOTS_WIDTH" default:"1280"`
+		Height   int  `yaml:"height" envconfig:"SCREENSHOTS_HEIGHT" default:"800"`
+		FullPage bool `yaml:"fullPage" envconfig:"SCREENSHOTS_FULL_PAGE"`
+	} `yaml:"screenshots"`
+	Blobs struct {
+		// Backend of the store of screenshots and import datasets, filesystem or gridfs
+		Backend string `yaml:"backend" envconfig:"BLOBS_BACKEND" default:"filesystem"`
+		// Directory of the filesystem backend, an absolute path shared with the server to import datasets from the CLI
+		// with --resolve
+		Dir string `yaml:"dir" envconfig:"BLOBS_DIR" default:"./blobs"`
+	} `yaml:"blobs"`
+	Technologies struct {
+		// Wappalyzer-style signatures replacing the bundled ones
+		File string `yaml:"file" envconfig:"TECHNOLOGIES_FILE"`
+		// Wappalyzer categories naming the numeric categories of the signatures
+		Categories string `yaml:"categories" envconfig:"TECHNOLOGIES_CATEGORIES"`
+		AutoTag    bool   `yaml:"autoTag" envconfig:"TECHNOLOGIES_AUTOTAG" default:"true"`
+	} `yaml:"technologies"`
 }
 
 var (
@@ -27,17 +81,36 @@ var (
 
 func Init() {
 	once.Do(func() {
-		readFile(&Cfg)
-		readEnv(&Cfg)
+		Cfg = load("config.yml")
 	})
 }
 
+// load : returns the configuration with, from the lowest precedence, the defaults of the tags, the settings of a
+// YAML file and the environment variables which are set
+func load(path string) Config {
+	var cfg Config
+	readDefaults(&cfg)
+	readFile(&cfg, path)
+	readEnv(&cfg)
+	return cfg
+}
+
 func processError(err error) {
 	fmt.Println(err)
 }
 
-func readFile(cfg *Config) {
-	f, err := os.Open("config.yml")
+func readDefaults(cfg *Config) {
+	err := setFields(reflect.ValueOf(cfg).Elem(), func(field reflect.StructField) (string, bool) {
+		def := field.Tag.Get("default")
+		return def, def != ""
+	})
+	if err != nil {
+		processError(err)
+	}
+}
+
+func readFile(cfg *Config, path string) {
+	f, err := os.Open(path)
 	if err != nil {
 		processError(err)
 		return
@@ -51,9 +124,81 @@ func readFile(cfg *Config) {
 	}
 }
 
+// readEnv : only the variables which are set replace the settings, a required setting must be given by one of them or
+// by the file
 func readEnv(cfg *Config) {
-	err := envconfig.Process("", cfg)
+	err := setFields(reflect.ValueOf(cfg).Elem(), func(field reflect.StructField) (string, bool) {
+		key := field.Tag.Get("envconfig")
+		if key == "" {
+			return "", false
+		}
+		return os.LookupEnv(key)
+	})
 	if err != nil {
 		processError(err)
 	}
+	checkRequired(reflect.ValueOf(cfg).Elem())
+}
+
+// setFields : sets the fields of a struct and of its nested structs to the values lookup finds for them
+func setFields(v reflect.Value, lookup func(field reflect.StructField) (string, bool)) error {
+	for idx := 0; idx < v.NumField(); idx++ {
+		field, value := v.Type().Field(idx), v.Field(idx)
+		if value.Kind() == reflect.Struct {
+			if err := setFields(value, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+		s, ok := lookup(field)
+		if !ok {
+			continue
+		}
+		if err := setField(value, s); err != nil {
+			return fmt.Errorf("invalid value %q of %s : %w", s, field.Name, err)
+		}
+	}
+	return nil
+}
+
+func setField(value reflect.Value, s string) error {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Slice:
+		// Comma separated list
+		items := make([]string, 0)
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+func checkRequired(v reflect.Value) {
+	for idx := 0; idx < v.NumField(); idx++ {
+		field, value := v.Type().Field(idx), v.Field(idx)
+		if value.Kind() == reflect.Struct {
+			checkRequired(value)
+		} else if field.Tag.Get("required") == "true" && value.IsZero() {
+			processError(fmt.Errorf("required key %s missing value", field.Tag.Get("envconfig")))
+		}
+	}
 }
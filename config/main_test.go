@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	err := os.WriteFile(path, []byte(`
+database:
+  uri: mongodb://db:27017
+  name: faryuk
+jobs:
+  workers: 42
+  timeout: 600
+buster:
+  threads: 3
+targets:
+  maxSize: 4096
+technologies:
+  autoTag: false
+screenshots:
+  browsers: 1
+  width: 1920
+blobs:
+  backend: gridfs
+dns:
+  threads: 50
+  maxPermutations: 100
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JOBS_QUEUE_SIZE", "20")
+	t.Setenv("JOBS_TIMEOUT", "60")
+
+	cfg := load(path)
+	tests := []struct {
+		setting string
+		got     interface{}
+		want    interface{}
+	}{
+		{"server.port default", cfg.Server.Port, 4444},
+		{"database.name from the file", cfg.Database.Name, "faryuk"},
+		{"jobs.workers from the file", cfg.Jobs.Workers, 42},
+		{"jobs.queueSize from the environment", cfg.Jobs.QueueSize, 20},
+		{"jobs.timeout from the environment over the file", cfg.Jobs.Timeout, 60},
+		{"buster.threads from the file", cfg.Buster.Threads, 3},
+		{"targets.maxSize from the file", cfg.Targets.MaxSize, 4096},
+		{"technologies.autoTag from the file", cfg.Technologies.AutoTag, false},
+		{"screenshots.browsers from the file", cfg.Screenshots.Browsers, 1},
+		{"screenshots.width from the file", cfg.Screenshots.Width, 1920},
+		{"screenshots.height default", cfg.Screenshots.Height, 800},
+		{"blobs.backend from the file", cfg.Blobs.Backend, "gridfs"},
+		{"blobs.dir default", cfg.Blobs.Dir, "./blobs"},
+		{"dns.threads from the file", cfg.DNS.Threads, 50},
+		{"dns.maxPermutations from the file", cfg.DNS.MaxPermutations, 100},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s : got %v, want %v", tt.setting, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	t.Setenv("DNS_RESOLVERS", "1.1.1.1, 9.9.9.9")
+	t.Setenv("JOBS_WORKERS", "2")
+
+	cfg := load(filepath.Join(t.TempDir(), "missing.yml"))
+	if len(cfg.DNS.Resolvers) != 2 || cfg.DNS.Resolvers[1] != "9.9.9.9" {
+		t.Errorf("got resolvers %q, want 1.1.1.1 and 9.9.9.9", cfg.DNS.Resolvers)
+	}
+	if cfg.Jobs.Workers != 2 || cfg.Jobs.QueueSize != 1000 {
+		t.Errorf("got %d workers and a queue of %d, want 2 and the default 1000", cfg.Jobs.Workers, cfg.Jobs.QueueSize)
+	}
+}
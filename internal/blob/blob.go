@@ -0,0 +1,36 @@
+package blob
+
+import (
+	"errors"
+	"fmt"
+
+	"FaRyuk/config"
+)
+
+// Backends of the blob store
+const (
+	BackendFilesystem = "filesystem"
+	BackendGridFS     = "gridfs"
+)
+
+// ErrNotFound : returned when a blob does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// Store : storage of binary objects such as screenshots, kept out of the result documents
+type Store interface {
+	Put(id string, data []byte) error
+	Get(id string) ([]byte, error)
+	Remove(id string) error
+}
+
+// NewStore : returns the store of a bucket with the backend of the configuration
+func NewStore(bucket string) (Store, error) {
+	cfg := config.Cfg.Blobs
+	switch cfg.Backend {
+	case BackendFilesystem, "":
+		return NewFileStore(cfg.Dir, bucket), nil
+	case BackendGridFS:
+		return NewGridFSStore(bucket), nil
+	}
+	return nil, fmt.Errorf("unknown blob store backend %s", cfg.Backend)
+}
@@ -0,0 +1,65 @@
+package blob
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStore : blobs stored as files of a directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore : returns a FileStore keeping the blobs of a bucket in a sub-directory of dir
+func NewFileStore(dir, bucket string) *FileStore {
+	return &FileStore{filepath.Join(dir, bucket)}
+}
+
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid blob id %s", id)
+	}
+	return filepath.Join(s.dir, id), nil
+}
+
+// Put : writes a blob, it replaces the blob with the same ID
+func (s *FileStore) Put(id string, data []byte) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(s.dir, 0o750)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o640)
+}
+
+// Get : reads a blob
+func (s *FileStore) Get(id string) ([]byte, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Remove : deletes a blob
+func (s *FileStore) Remove(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package blob
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"FaRyuk/config"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir, "screenshots")
+	other := NewFileStore(dir, "imports")
+
+	_, err := store.Get("missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v reading a missing blob, want %v", err, ErrNotFound)
+	}
+
+	err = store.Put("id", []byte("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Put("id", []byte("second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := store.Get("id")
+	if err != nil || string(data) != "second" {
+		t.Errorf("got %q, %v, want the blob written last", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "screenshots", "id")); err != nil {
+		t.Errorf("the blob is not in the directory of its bucket : %v", err)
+	}
+	if _, err := other.Get("id"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v reading the blob from another bucket, want %v", err, ErrNotFound)
+	}
+
+	err = store.Remove("id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("id"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v reading a removed blob, want %v", err, ErrNotFound)
+	}
+	if err := store.Remove("id"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v removing a missing blob, want %v", err, ErrNotFound)
+	}
+}
+
+func TestFileStoreInvalidIDs(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(filepath.Join(dir, "store"), "screenshots")
+	for _, id := range []string{"", "../escape", `..\escape`, "a/b", ".hidden", ".."} {
+		if err := store.Put(id, []byte("data")); err == nil {
+			t.Errorf("blob %q was written", id)
+		}
+		if _, err := store.Get(id); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v reading blob %q, want an invalid id error", err, id)
+		}
+		if err := store.Remove(id); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("got %v removing blob %q, want an invalid id error", err, id)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("invalid blobs created %d entries", len(entries))
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	backend := config.Cfg.Blobs.Backend
+	t.Cleanup(func() { config.Cfg.Blobs.Backend = backend })
+
+	config.Cfg.Blobs.Backend = ""
+	store, err := NewStore("screenshots")
+	if _, ok := store.(*FileStore); err != nil || !ok {
+		t.Errorf("got %T, %v without a backend, want the filesystem store", store, err)
+	}
+	config.Cfg.Blobs.Backend = "s3"
+	if _, err := NewStore("screenshots"); err == nil {
+		t.Error("got a store for an unknown backend")
+	}
+}
@@ -0,0 +1,48 @@
+package blob
+
+import (
+	"errors"
+
+	"FaRyuk/internal/db"
+
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+)
+
+// GridFSStore : blobs stored in a GridFS bucket of the database
+type GridFSStore struct {
+	bucket string
+}
+
+// NewGridFSStore : returns a GridFSStore using a bucket
+func NewGridFSStore(bucket string) *GridFSStore {
+	return &GridFSStore{bucket}
+}
+
+// Put : writes a blob, it replaces the blob with the same ID
+func (s *GridFSStore) Put(id string, data []byte) error {
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+	return dbHandler.PutBlob(s.bucket, id, data)
+}
+
+// Get : reads a blob
+func (s *GridFSStore) Get(id string) ([]byte, error) {
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+	data, err := dbHandler.GetBlob(s.bucket, id)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Remove : deletes a blob
+func (s *GridFSStore) Remove(id string) error {
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+	err := dbHandler.RemoveBlob(s.bucket, id)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
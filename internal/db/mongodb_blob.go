@@ -0,0 +1,48 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+
+	"FaRyuk/config"
+
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (db *Handler) bucket(name string) (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(db.client.Database(config.Cfg.Database.Name), options.GridFSBucket().SetName(name))
+}
+
+// PutBlob : stores a binary object in a GridFS bucket, an object with the same ID is replaced
+func (db *Handler) PutBlob(bucketName, id string, data []byte) error {
+	bucket, err := db.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	err = bucket.Delete(id)
+	if err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+		return err
+	}
+	return bucket.UploadFromStreamWithID(id, id, bytes.NewReader(data))
+}
+
+// GetBlob : returns a binary object of a GridFS bucket
+func (db *Handler) GetBlob(bucketName, id string) ([]byte, error) {
+	bucket, err := db.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	_, err = bucket.DownloadToStream(id, &buf)
+	return buf.Bytes(), err
+}
+
+// RemoveBlob : removes a binary object of a GridFS bucket
+func (db *Handler) RemoveBlob(bucketName, id string) error {
+	bucket, err := db.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	return bucket.Delete(id)
+}
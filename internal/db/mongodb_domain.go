@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+
+	"FaRyuk/config"
+	"FaRyuk/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InsertDomainResult : inserts a domain result in the database
+func (db *Handler) InsertDomainResult(r *types.DomainResult) error {
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("domain_results")
+	_, err := collection.InsertOne(context.TODO(), r)
+	return err
+}
+
+// UpdateDomainResult : updates a domain result
+func (db *Handler) UpdateDomainResult(r *types.DomainResult) error {
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("domain_results")
+	_, err := collection.UpdateOne(context.TODO(), bson.M{"id": r.ID}, bson.M{"$set": r})
+	return err
+}
+
+// UpdateDomainResultBruteForce : updates the state of the subdomain brute force of a domain result
+func (db *Handler) UpdateDomainResultBruteForce(id string, state *types.BruteForceState) error {
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("domain_results")
+	_, err := collection.UpdateOne(context.TODO(), bson.M{"id": id}, bson.M{"$set": bson.M{"bruteForce": state}})
+	return err
+}
+
+// GetDomainResultByDomainAndOwner : returns the domain result of a domain scanned by a user
+func (db *Handler) GetDomainResultByDomainAndOwner(domain, idUser string) *types.DomainResult {
+	var result types.DomainResult
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("domain_results")
+	err := collection.FindOne(context.TODO(), bson.M{"domain": domain, "owner": idUser}).Decode(&result)
+	if err != nil {
+		return nil
+	}
+	return &result
+}
+
+// GetDomainResultByID : returns a domain result by ID
+func (db *Handler) GetDomainResultByID(id string) *types.DomainResult {
+	var result types.DomainResult
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("domain_results")
+	err := collection.FindOne(context.TODO(), bson.M{"id": id}).Decode(&result)
+	if err != nil {
+		return nil
+	}
+	return &result
+}
+
+// domainResultsFilter : returns the filter of the domain results matching search criteria, restricted to the ones of
+// a user and of their groups unless idUser is empty
+func domainResultsFilter(search map[string]string, idUser string, groups []string) bson.M {
+	filter := bson.M{
+		"domain":     bson.M{"$regex": ".*" + search["default"] + ".*"},
+		"ownerGroup": bson.M{"$regex": ".*" + search["group"] + ".*"},
+	}
+	if search["subdomain"] != "" {
+		filter["subdomains.name"] = bson.M{"$regex": ".*" + search["subdomain"] + ".*"}
+	}
+	if search["ip"] != "" {
+		filter["subdomains.ips"] = search["ip"]
+	}
+	if search["source"] != "" {
+		filter["subdomains.source"] = search["source"]
+	}
+	if search["finding"] != "" {
+		filter["findings.kind"] = search["finding"]
+	}
+	if idUser != "" {
+		filter["$or"] = []interface{}{
+			bson.M{"owner": idUser},
+			bson.M{"ownerGroup": bson.M{"$in": groups}},
+		}
+	}
+	return filter
+}
+
+// GetDomainResultsBySearch : returns domain results by search criteria without the DNS records of their subdomains,
+// only the ones of a user and of their groups unless idUser is empty
+func (db *Handler) GetDomainResultsBySearch(search map[string]string,
+	idUser string,
+	groups []string,
+	offset int,
+	pageSize int) ([]types.DomainResult, error) {
+	results := make([]types.DomainResult, 0)
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("domain_results")
+
+	opts := options.Find().
+		SetSort(bson.M{"updatedDate": -1}).
+		SetProjection(bson.M{"subdomains.records": 0})
+	if pageSize != -1 {
+		opts.SetSkip(int64(offset)).SetLimit(int64(pageSize))
+	}
+
+	cur, err := collection.Find(context.TODO(), domainResultsFilter(search, idUser, groups), opts)
+	if err != nil {
+		return make([]types.DomainResult, 0), err
+	}
+	for cur.Next(context.TODO()) {
+		var elem types.DomainResult
+		err := cur.Decode(&elem)
+		if err != nil {
+			return make([]types.DomainResult, 0), err
+		}
+		results = append(results, elem)
+	}
+
+	if err := cur.Err(); err != nil {
+		return make([]types.DomainResult, 0), err
+	}
+
+	cur.Close(context.TODO())
+	return results, nil
+}
+
+// CountDomainResultsBySearch : returns the number of domain results matching search criteria, only the ones of a user
+// and of their groups unless idUser is empty
+func (db *Handler) CountDomainResultsBySearch(search map[string]string, idUser string, groups []string) (int, error) {
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("domain_results")
+	count, err := collection.CountDocuments(context.TODO(), domainResultsFilter(search, idUser, groups))
+	return int(count), err
+}
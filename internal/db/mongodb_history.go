@@ -124,6 +124,10 @@ func (db *Handler) GetHistoryRecordsBySearch(search map[string]string,
 		}
 	}
 
+	if err := db.historyEventFilter(filter, search); err != nil {
+		return make([]types.HistoryRecord, 0), err
+	}
+
 	skip := int64(offset)
 	limit := int64(pageSize)
 
@@ -200,6 +204,10 @@ func (db *Handler) GetHistoryRecordsBySearchAndOwner(search map[string]string,
 		}
 	}
 
+	if err := db.historyEventFilter(filter, search); err != nil {
+		return make([]types.HistoryRecord, 0), err
+	}
+
 	skip := int64(offset)
 	limit := int64(pageSize)
 
@@ -264,6 +272,10 @@ func (db *Handler) CountHistoryRecordsBySearch(search map[string]string) (int, e
 		}
 	}
 
+	if err := db.historyEventFilter(filter, search); err != nil {
+		return -1, err
+	}
+
 	cnt, err := collection.CountDocuments(context.TODO(), filter)
 	if err != nil {
 		return -1, err
@@ -306,6 +318,10 @@ func (db *Handler) CountHistoryRecordsBySearchAndOwner(search map[string]string,
 		}
 	}
 
+	if err := db.historyEventFilter(filter, search); err != nil {
+		return -1, err
+	}
+
 	cnt, err := collection.CountDocuments(context.TODO(), filter)
 	if err != nil {
 		return -1, err
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+
+	"FaRyuk/config"
+	"FaRyuk/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureHistoryEventIndexes : creates the indexes of the history events collection
+func (db *Handler) EnsureHistoryEventIndexes() error {
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("history_events")
+	_, err := collection.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "historyId", Value: 1}, {Key: "createdDate", Value: 1}}},
+		{Keys: bson.D{{Key: "stage", Value: 1}, {Key: "severity", Value: 1}}},
+		{Keys: bson.D{{Key: "severity", Value: 1}}},
+	})
+	return err
+}
+
+// InsertHistoryEvent : inserts a scan event in the database
+func (db *Handler) InsertHistoryEvent(e types.ScanEvent) error {
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("history_events")
+	_, err := collection.InsertOne(context.TODO(), e)
+	return err
+}
+
+// GetHistoryEvents : returns the events of the given history records, oldest first
+func (db *Handler) GetHistoryEvents(historyIDs []string) ([]types.ScanEvent, error) {
+	results := make([]types.ScanEvent, 0)
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("history_events")
+	opts := options.Find().SetSort(bson.M{"createdDate": 1})
+	cur, err := collection.Find(context.TODO(), bson.M{"historyId": bson.M{"$in": historyIDs}}, opts)
+	if err != nil {
+		return make([]types.ScanEvent, 0), err
+	}
+
+	for cur.Next(context.TODO()) {
+		var elem types.ScanEvent
+		err := cur.Decode(&elem)
+		if err != nil {
+			return make([]types.ScanEvent, 0), err
+		}
+		results = append(results, elem)
+	}
+
+	if err := cur.Err(); err != nil {
+		return make([]types.ScanEvent, 0), err
+	}
+
+	cur.Close(context.TODO())
+	return results, nil
+}
+
+// RemoveHistoryEvents : removes the events of a history record
+func (db *Handler) RemoveHistoryEvents(historyID string) error {
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("history_events")
+	_, err := collection.DeleteMany(context.TODO(), bson.M{"historyId": historyID})
+	return err
+}
+
+// GetHistoryIDsByEvent : returns the IDs of history records having events of a stage and/or a severity
+func (db *Handler) GetHistoryIDsByEvent(stage string, severity string) ([]string, error) {
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("history_events")
+	filter := bson.M{}
+	if stage != "" {
+		filter["stage"] = stage
+	}
+	if severity != "" {
+		filter["severity"] = severity
+	}
+
+	values, err := collection.Distinct(context.TODO(), "historyId", filter)
+	if err != nil {
+		return make([]string, 0), err
+	}
+
+	ids := make([]string, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// historyEventFilter : restricts a history records filter to records having events matching the search
+func (db *Handler) historyEventFilter(filter bson.M, search map[string]string) error {
+	if search["stage"] == "" && search["severity"] == "" {
+		return nil
+	}
+	ids, err := db.GetHistoryIDsByEvent(search["stage"], search["severity"])
+	if err != nil {
+		return err
+	}
+	filter["id"] = bson.M{"$in": ids}
+	return nil
+}
+
+// AppendHistoryState : appends a rendered line to the state of a history record
+func (db *Handler) AppendHistoryState(id string, line string) error {
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("history")
+	_, err := collection.UpdateOne(context.TODO(), bson.M{"id": id}, bson.M{"$push": bson.M{"state": line}})
+	return err
+}
+
+// FinishHistoryRecord : marks a history record as finished
+func (db *Handler) FinishHistoryRecord(id string, success bool) error {
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("history")
+	_, err := collection.UpdateOne(context.TODO(), bson.M{"id": id},
+		bson.M{"$set": bson.M{"isFinished": true, "isSuccess": success}})
+	return err
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+
+	"FaRyuk/config"
+	"FaRyuk/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InsertJob : inserts a job in the database
+func (db *Handler) InsertJob(j *types.Job) error {
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("jobs")
+	_, err := collection.InsertOne(context.TODO(), j)
+	return err
+}
+
+// UpdateJob : updates a job
+func (db *Handler) UpdateJob(j *types.Job) error {
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("jobs")
+	_, err := collection.UpdateOne(context.TODO(), bson.M{"id": j.ID}, bson.M{"$set": j})
+	return err
+}
+
+// GetJobByID : returns a job by ID
+func (db *Handler) GetJobByID(id string) (types.Job, error) {
+	var result types.Job
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("jobs")
+	err := collection.FindOne(context.TODO(), bson.M{"id": id}).Decode(&result)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// getJobsByFilter : returns jobs matching a filter, most recent first
+func (db *Handler) getJobsByFilter(filter bson.M) ([]types.Job, error) {
+	results := make([]types.Job, 0)
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("jobs")
+	opts := options.Find().SetSort(bson.M{"createdDate": -1})
+	cur, err := collection.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return make([]types.Job, 0), err
+	}
+
+	for cur.Next(context.TODO()) {
+		var elem types.Job
+		err := cur.Decode(&elem)
+		if err != nil {
+			return make([]types.Job, 0), err
+		}
+		results = append(results, elem)
+	}
+
+	if err := cur.Err(); err != nil {
+		return make([]types.Job, 0), err
+	}
+
+	cur.Close(context.TODO())
+	return results, nil
+}
+
+// GetJobs : returns all jobs, optionally filtered by state
+func (db *Handler) GetJobs(state string) ([]types.Job, error) {
+	filter := bson.M{}
+	if state != "" {
+		filter["state"] = state
+	}
+	return db.getJobsByFilter(filter)
+}
+
+// GetJobsByParent : returns the jobs queued by a job
+func (db *Handler) GetJobsByParent(parentID string) ([]types.Job, error) {
+	return db.getJobsByFilter(bson.M{"parentId": parentID})
+}
+
+// GetJobsByOwner : returns all jobs of a given owner, optionally filtered by state
+func (db *Handler) GetJobsByOwner(idUser string, state string) ([]types.Job, error) {
+	filter := bson.M{"owner": idUser}
+	if state != "" {
+		filter["state"] = state
+	}
+	return db.getJobsByFilter(filter)
+}
@@ -16,6 +16,68 @@ import (
 
 const emptyResult = "empty"
 
+// busterFields : search keys matching fields of the GoBuster results
+var busterFields = map[string]string{
+	"buster":      "path",
+	"title":       "title",
+	"location":    "location",
+	"contentType": "contentType",
+	"hash":        "hash",
+}
+
+// serviceFields : search keys matching fields of the detected services
+var serviceFields = map[string]string{
+	"service": "name",
+	"product": "product",
+	"version": "version",
+	"banner":  "banner",
+}
+
+// fieldsFilter : restricts a results filter to results having an element of the array whose fields all match the
+// search, the same element has to match every field searched
+func fieldsFilter(filter bson.M, search map[string]string, array string, fields map[string]string) {
+	match := bson.M{}
+	for key, field := range fields {
+		if search[key] != "" {
+			match[field] = bson.M{"$regex": ".*" + search[key] + ".*"}
+		}
+	}
+	if len(match) != 0 {
+		filter[array] = bson.M{"$elemMatch": match}
+	}
+}
+
+// busterFilter : restricts a results filter to results having GoBuster results matching the search
+func busterFilter(filter bson.M, search map[string]string) {
+	fieldsFilter(filter, search, "webResults.busterres", busterFields)
+}
+
+// techFilter : restricts a results filter to results using a technology, the name is case insensitive
+func techFilter(filter bson.M, search map[string]string) {
+	if search["tech"] != "" {
+		filter["webResults.technologies.name"] = bson.M{"$regex": ".*" + search["tech"] + ".*", "$options": "i"}
+	}
+}
+
+// checkFilter : restricts a results filter to results failing a check of the headers audit
+func checkFilter(filter bson.M, search map[string]string) {
+	if search["check"] != "" {
+		filter["webResults.audit.findings.check"] = strings.ToLower(search["check"])
+	}
+}
+
+// serviceFilter : restricts a results filter to results having services matching the search
+func serviceFilter(filter bson.M, search map[string]string) {
+	fieldsFilter(filter, search, "services", serviceFields)
+}
+
+// udpPortsFilter : restricts a results filter to results having all the searched UDP ports open
+func udpPortsFilter(filter bson.M, search map[string]string) {
+	if ports := helper.ParseInts(search["udp"]); len(ports) != 0 {
+		filter["openUdpPorts.port"] = bson.M{"$all": ports}
+	}
+}
+
 // InsertResult : inserts result in the database
 func (db *Handler) InsertResult(r *types.Result) error {
 	collection := db.client.Database(config.Cfg.Database.Name).Collection("results")
@@ -69,6 +131,28 @@ func (db *Handler) UpdateResult(r *types.Result) bool {
 	return err == nil
 }
 
+// GetResultByScreenshot : returns the result a screenshot belongs to
+func (db *Handler) GetResultByScreenshot(id string) *types.Result {
+	var result types.Result
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("results")
+	err := collection.FindOne(context.TODO(), bson.M{"webResults.screener.id": id}).Decode(&result)
+	if err != nil {
+		return nil
+	}
+	return &result
+}
+
+// GetResultWithLegacyScreenshot : returns a result with a screenshot still stored as base64 in the document
+func (db *Handler) GetResultWithLegacyScreenshot() *types.Result {
+	var result types.Result
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("results")
+	err := collection.FindOne(context.TODO(), bson.M{"webResults.screener.path": bson.M{"$gt": ""}}).Decode(&result)
+	if err != nil {
+		return nil
+	}
+	return &result
+}
+
 // GetResultByID : returns a result by ID
 func (db *Handler) GetResultByID(id string) *types.Result {
 	var result types.Result
@@ -99,9 +183,11 @@ func (db *Handler) GetResultsBySearch(search map[string]string, offset, pageSize
 		filter["openPorts"] = bson.M{"$ne": make([]int, 0)}
 	}
 
-	if search["buster"] != "" {
-		filter["webResults.busterres.path"] = bson.M{"$regex": ".*" + search["buster"] + ".*"}
-	}
+	busterFilter(filter, search)
+	udpPortsFilter(filter, search)
+	serviceFilter(filter, search)
+	techFilter(filter, search)
+	checkFilter(filter, search)
 
 	if search["tags"] != "" {
 		searchTags := make([]string, 0)
@@ -175,9 +261,11 @@ func (db *Handler) GetResultsBySearchAndOwner(search map[string]string,
 		filter["openPorts"] = bson.M{"$ne": make([]int, 0)}
 	}
 
-	if search["buster"] != "" {
-		filter["webResults.busterres.path"] = bson.M{"$regex": ".*" + search["buster"] + ".*"}
-	}
+	busterFilter(filter, search)
+	udpPortsFilter(filter, search)
+	serviceFilter(filter, search)
+	techFilter(filter, search)
+	checkFilter(filter, search)
 
 	if search["tags"] != "" {
 		searchTags := make([]string, 0)
@@ -236,9 +324,11 @@ func (db *Handler) CountResultsBySearch(search map[string]string) (int, error) {
 		filter["openPorts"] = bson.M{"$ne": make([]int, 0)}
 	}
 
-	if search["buster"] != "" {
-		filter["webResults.busterres.path"] = bson.M{"$regex": ".*" + search["buster"] + ".*"}
-	}
+	busterFilter(filter, search)
+	udpPortsFilter(filter, search)
+	serviceFilter(filter, search)
+	techFilter(filter, search)
+	checkFilter(filter, search)
 
 	if search["tags"] != "" {
 		searchTags := make([]string, 0)
@@ -276,9 +366,11 @@ func (db *Handler) CountResultsBySearchAndOwner(search map[string]string, groups
 		filter["openPorts"] = bson.M{"$ne": make([]int, 0)}
 	}
 
-	if search["buster"] != "" {
-		filter["webResults.busterres.path"] = bson.M{"$regex": ".*" + search["buster"] + ".*"}
-	}
+	busterFilter(filter, search)
+	udpPortsFilter(filter, search)
+	serviceFilter(filter, search)
+	techFilter(filter, search)
+	checkFilter(filter, search)
 
 	if search["tags"] != "" {
 		searchTags := make([]string, 0)
@@ -295,6 +387,34 @@ func (db *Handler) CountResultsBySearchAndOwner(search map[string]string, groups
 	return int(cnt), nil
 }
 
+// GetResultsByHostsAndOwner : returns the results of a user scanning any of hosts, only their ID and host are read
+func (db *Handler) GetResultsByHostsAndOwner(hosts []string, idUser string) ([]types.Result, error) {
+	results := make([]types.Result, 0)
+	collection := db.client.Database(config.Cfg.Database.Name).Collection("results")
+	filter := bson.M{"host": bson.M{"$in": hosts}, "owner": idUser}
+	opts := options.Find().SetProjection(bson.M{"id": 1, "host": 1})
+
+	cur, err := collection.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return make([]types.Result, 0), err
+	}
+	for cur.Next(context.TODO()) {
+		var elem types.Result
+		err := cur.Decode(&elem)
+		if err != nil {
+			return make([]types.Result, 0), err
+		}
+		results = append(results, elem)
+	}
+
+	if err := cur.Err(); err != nil {
+		return make([]types.Result, 0), err
+	}
+
+	cur.Close(context.TODO())
+	return results, nil
+}
+
 // GetResultsByHostAndOwner : returns all results matching search host and that a user can access
 func (db *Handler) GetResultsByHostAndOwner(search, idUser string) ([]types.Result, error) {
 	var results []types.Result
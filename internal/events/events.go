@@ -0,0 +1,140 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"FaRyuk/internal/types"
+)
+
+// Event kinds
+const (
+	KindInfo          = "info"
+	KindStageStarted  = "stage-started"
+	KindStageFinished = "stage-finished"
+	KindStageFailed   = "stage-failed"
+	KindProgress      = "progress"
+	KindBusterHit     = "buster-hit"
+	KindRunnerOutput  = "runner-output"
+	KindScanFinished  = "scan-finished"
+)
+
+// Scan stages
+const (
+	StageScan       = "scan"
+	StageResolution = "resolution"
+	StagePortScan   = "portscan"
+	StageUDPScan    = "udpscan"
+	StageServices   = "services"
+	StageHeaders    = "headers"
+	StageAudit      = "audit"
+	StageTLS        = "tls"
+	StageTech       = "tech"
+	StageScreenshot = "screenshot"
+	StageBuster     = "buster"
+	StageRunner     = "runner"
+	StageDNS        = "dns"
+	StageRecords    = "records"
+	StageTakeover   = "takeover"
+	StageAXFR       = "axfr"
+	StagePermute    = "permutations"
+)
+
+// Event severities
+const (
+	SeverityInfo    = "info"
+	SeveritySuccess = "success"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// subscriberBuffer : events kept for a slow subscriber before dropping new ones, the channel of a subscriber is closed
+// once the scan is finished so that the end is never missed
+const subscriberBuffer = 64
+
+// Bus : in-process publish/subscribe of scan events, keyed by history record
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan types.ScanEvent]bool
+}
+
+var defaultBus = NewBus()
+
+// NewBus : returns a new Bus
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[string]map[chan types.ScanEvent]bool)}
+}
+
+// Subscribe : returns a channel receiving the events of a history record and a function to unsubscribe
+func (b *Bus) Subscribe(historyID string) (<-chan types.ScanEvent, func()) {
+	ch := make(chan types.ScanEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[historyID] == nil {
+		b.subscribers[historyID] = make(map[chan types.ScanEvent]bool)
+	}
+	b.subscribers[historyID][ch] = true
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			// The channel is already closed when the scan finished
+			if !b.subscribers[historyID][ch] {
+				return
+			}
+			delete(b.subscribers[historyID], ch)
+			if len(b.subscribers[historyID]) == 0 {
+				delete(b.subscribers, historyID)
+			}
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// Publish : sends an event to the subscribers of its history record, it never blocks. The subscribers are removed and
+// their channels closed when the scan is finished
+func (b *Bus) Publish(e types.ScanEvent) {
+	if e.CreatedDate.IsZero() {
+		e.CreatedDate = time.Now()
+	}
+
+	if e.Kind == KindScanFinished {
+		b.mu.Lock()
+		subscribers := b.subscribers[e.HistoryID]
+		delete(b.subscribers, e.HistoryID)
+		b.mu.Unlock()
+		for ch := range subscribers {
+			select {
+			case ch <- e:
+			default:
+				// The closed channel still tells a slow subscriber that the scan is finished
+			}
+			close(ch)
+		}
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subscribers[e.HistoryID] {
+		select {
+		case ch <- e:
+		default:
+			// Subscriber is too slow, drop the event rather than stalling the scan
+		}
+	}
+}
+
+// Subscribe : subscribes to the default bus
+func Subscribe(historyID string) (<-chan types.ScanEvent, func()) {
+	return defaultBus.Subscribe(historyID)
+}
+
+// Publish : publishes on the default bus
+func Publish(e types.ScanEvent) {
+	defaultBus.Publish(e)
+}
@@ -0,0 +1,55 @@
+package events
+
+import (
+	"testing"
+
+	"FaRyuk/internal/types"
+)
+
+func TestBusPublishFinishedToSlowSubscriber(t *testing.T) {
+	bus := NewBus()
+	ch, unsubscribe := bus.Subscribe("history")
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBuffer*2; i++ {
+		bus.Publish(types.ScanEvent{HistoryID: "history", Kind: KindRunnerOutput, Message: "output"})
+	}
+	bus.Publish(types.ScanEvent{HistoryID: "history", Kind: KindScanFinished})
+
+	received := 0
+	for range ch {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("received %d events, want the %d buffered", received, subscriberBuffer)
+	}
+
+	// Events published after the end are not sent anywhere
+	bus.Publish(types.ScanEvent{HistoryID: "history", Kind: KindInfo})
+	unsubscribe()
+}
+
+func TestBusPublishFinished(t *testing.T) {
+	bus := NewBus()
+	ch, unsubscribe := bus.Subscribe("history")
+	other, unsubscribeOther := bus.Subscribe("other")
+	defer unsubscribe()
+	defer unsubscribeOther()
+
+	bus.Publish(types.ScanEvent{HistoryID: "history", Kind: KindInfo})
+	bus.Publish(types.ScanEvent{HistoryID: "history", Kind: KindScanFinished})
+
+	kinds := make([]string, 0)
+	for e := range ch {
+		kinds = append(kinds, e.Kind)
+	}
+	if len(kinds) != 2 || kinds[0] != KindInfo || kinds[1] != KindScanFinished {
+		t.Errorf("received %v, want the info and the end of the scan", kinds)
+	}
+
+	select {
+	case e := <-other:
+		t.Errorf("the subscriber of another scan received %v", e)
+	default:
+	}
+}
@@ -0,0 +1,191 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"FaRyuk/internal/db"
+	"FaRyuk/internal/types"
+
+	"github.com/google/uuid"
+)
+
+// stageNames : names of the stages used when rendering state lines
+var stageNames = map[string]string{
+	StageScan:       "Scan",
+	StageResolution: "Resolution",
+	StagePortScan:   "Port scanning",
+	StageUDPScan:    "UDP port scanning",
+	StageServices:   "Service detection",
+	StageHeaders:    "Headers grabbing",
+	StageAudit:      "Security headers audit",
+	StageTLS:        "TLS analysis",
+	StageTech:       "Technology fingerprinting",
+	StageScreenshot: "Screenshot",
+	StageBuster:     "GoBuster",
+	StageRunner:     "Runner",
+	StageDNS:        "DNS scan",
+	StageRecords:    "DNS records",
+	StageTakeover:   "Takeover detection",
+	StageAXFR:       "Zone transfer",
+	StagePermute:    "Subdomain permutations",
+}
+
+// Recorder : records the events of a scan, they are stored, published and rendered in the history state
+type Recorder struct {
+	dbHandler *db.Handler
+	HistoryID string
+}
+
+// NewRecorder : returns a new Recorder for a history record
+func NewRecorder(dbHandler *db.Handler, historyID string) *Recorder {
+	return &Recorder{dbHandler, historyID}
+}
+
+// Emit : records an event
+func (r *Recorder) Emit(e types.ScanEvent) {
+	e.ID = uuid.New().String()
+	e.HistoryID = r.HistoryID
+	e.CreatedDate = time.Now()
+	if e.Severity == "" {
+		e.Severity = defaultSeverity(e.Kind)
+	}
+
+	// Hits and runner output are only streamed, results already keep them
+	if e.Kind != KindBusterHit && e.Kind != KindRunnerOutput {
+		if err := r.dbHandler.InsertHistoryEvent(e); err != nil {
+			log.Println(err)
+		}
+		if line := Render(e); line != "" {
+			if err := r.dbHandler.AppendHistoryState(r.HistoryID, line); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+	Publish(e)
+}
+
+// Info : records an informative message
+func (r *Recorder) Info(message string) {
+	r.Emit(types.ScanEvent{Kind: KindInfo, Stage: StageScan, Message: message})
+}
+
+// Started : records the start of a stage
+func (r *Recorder) Started(stage string, port int, tool string) {
+	r.Emit(types.ScanEvent{Kind: KindStageStarted, Stage: stage, Port: port, Tool: tool})
+}
+
+// Finished : records the success of a stage and how many items it found
+func (r *Recorder) Finished(stage string, port int, tool string, count int) {
+	r.Emit(types.ScanEvent{Kind: KindStageFinished, Stage: stage, Port: port, Tool: tool, Count: count})
+}
+
+// Failed : records the failure of a stage
+func (r *Recorder) Failed(stage string, port int, tool string, err error) {
+	r.Emit(types.ScanEvent{Kind: KindStageFailed, Stage: stage, Port: port, Tool: tool, Err: err.Error()})
+}
+
+// Finish : closes the history record according to the outcome of the scan
+func (r *Recorder) Finish(ctx context.Context, err error) {
+	e := types.ScanEvent{Kind: KindScanFinished, Stage: StageScan}
+	switch {
+	case ctx.Err() != nil:
+		e.Message = "cancelled"
+		e.Severity = SeverityWarning
+		e.Err = ctx.Err().Error()
+	case err != nil:
+		e.Message = "failed"
+		e.Severity = SeverityError
+		e.Err = err.Error()
+	default:
+		e.Message = "success"
+		e.Severity = SeveritySuccess
+	}
+
+	if err := r.dbHandler.FinishHistoryRecord(r.HistoryID, e.Severity == SeveritySuccess); err != nil {
+		log.Println(err)
+	}
+	r.Emit(e)
+}
+
+func defaultSeverity(kind string) string {
+	switch kind {
+	case KindStageFinished, KindBusterHit:
+		return SeveritySuccess
+	case KindStageFailed:
+		return SeverityError
+	}
+	return SeverityInfo
+}
+
+// Render : renders an event as a legacy history state line, empty if it has no such rendering
+func Render(e types.ScanEvent) string {
+	subject := stageNames[e.Stage]
+	if e.Tool != "" {
+		subject = e.Tool
+	}
+	suffix := ""
+	if e.Port != 0 {
+		suffix = fmt.Sprintf(" for port %d", e.Port)
+	}
+
+	switch e.Kind {
+	case KindInfo:
+		return "[*] " + e.Message
+	case KindStageStarted:
+		return fmt.Sprintf("[*] %s started%s", subject, suffix)
+	case KindStageFinished:
+		line := fmt.Sprintf("[+] %s finished%s", subject, suffix)
+		if e.Ports != nil {
+			line += fmt.Sprintf(" : %v", e.Ports)
+		} else if e.Stage == StageBuster || e.Stage == StageDNS || e.Stage == StagePermute {
+			line += fmt.Sprintf(" / Found : %d", e.Count)
+		}
+		return line
+	case KindStageFailed:
+		return fmt.Sprintf("[-] %s failed%s / Error : %s", subject, suffix, e.Err)
+	case KindProgress:
+		return fmt.Sprintf("[*] %s : %s, found : %d", subject, e.Message, e.Count)
+	case KindScanFinished:
+		switch e.Severity {
+		case SeveritySuccess:
+			return "[+] Scan finished"
+		case SeverityWarning:
+			return "[-] Scan cancelled"
+		}
+		return fmt.Sprintf("[-] Scan failed : %s", e.Err)
+	}
+	return ""
+}
+
+// Durations : computes how long each stage took from its started and finished or failed events
+func Durations(evts []types.ScanEvent) []types.StageDuration {
+	res := make([]types.StageDuration, 0)
+	running := make(map[string]int)
+	for _, e := range evts {
+		key := fmt.Sprintf("%s|%d|%s", e.Stage, e.Port, e.Tool)
+		switch e.Kind {
+		case KindStageStarted:
+			running[key] = len(res)
+			res = append(res, types.StageDuration{
+				Stage:       e.Stage,
+				Port:        e.Port,
+				Tool:        e.Tool,
+				Severity:    SeverityInfo,
+				StartedDate: e.CreatedDate,
+			})
+		case KindStageFinished, KindStageFailed:
+			idx, ok := running[key]
+			if !ok {
+				continue
+			}
+			delete(running, key)
+			res[idx].Severity = e.Severity
+			res[idx].FinishedDate = e.CreatedDate
+			res[idx].Duration = e.CreatedDate.Sub(res[idx].StartedDate).Seconds()
+		}
+	}
+	return res
+}
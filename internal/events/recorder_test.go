@@ -0,0 +1,71 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"FaRyuk/internal/types"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		event types.ScanEvent
+		want  string
+	}{
+		{types.ScanEvent{Kind: KindInfo, Message: "Scan started"}, "[*] Scan started"},
+		{types.ScanEvent{Kind: KindStageStarted, Stage: StagePortScan}, "[*] Port scanning started"},
+		{types.ScanEvent{Kind: KindStageStarted, Stage: StageRunner, Tool: "nuclei", Port: 443}, "[*] nuclei started for port 443"},
+		{types.ScanEvent{Kind: KindStageFinished, Stage: StagePortScan, Ports: []int{22, 80}}, "[+] Port scanning finished : [22 80]"},
+		{types.ScanEvent{Kind: KindStageFinished, Stage: StageBuster, Port: 80, Count: 3}, "[+] GoBuster finished for port 80 / Found : 3"},
+		{types.ScanEvent{Kind: KindStageFinished, Stage: StageDNS, Count: 12}, "[+] DNS scan finished / Found : 12"},
+		{types.ScanEvent{Kind: KindStageFinished, Stage: StageTLS, Port: 443, Count: 2}, "[+] TLS analysis finished for port 443"},
+		{types.ScanEvent{Kind: KindStageFailed, Stage: StageScreenshot, Port: 80, Err: "timeout"}, "[-] Screenshot failed for port 80 / Error : timeout"},
+		{types.ScanEvent{Kind: KindProgress, Stage: StageDNS, Message: "1000/5000", Count: 4}, "[*] DNS scan : 1000/5000, found : 4"},
+		{types.ScanEvent{Kind: KindScanFinished, Severity: SeveritySuccess}, "[+] Scan finished"},
+		{types.ScanEvent{Kind: KindScanFinished, Severity: SeverityWarning}, "[-] Scan cancelled"},
+		{types.ScanEvent{Kind: KindScanFinished, Severity: SeverityError, Err: "no route to host"}, "[-] Scan failed : no route to host"},
+		{types.ScanEvent{Kind: KindBusterHit, Path: "/admin", StatusCode: 200}, ""},
+		{types.ScanEvent{Kind: KindRunnerOutput, Message: "output"}, ""},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.event); got != tt.want {
+			t.Errorf("Render(%+v) = %q, want %q", tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestDurations(t *testing.T) {
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+	evts := []types.ScanEvent{
+		{Kind: KindInfo, CreatedDate: at(0)},
+		{Kind: KindStageStarted, Stage: StagePortScan, CreatedDate: at(0)},
+		{Kind: KindStageFinished, Stage: StagePortScan, Severity: SeveritySuccess, CreatedDate: at(10)},
+		{Kind: KindStageStarted, Stage: StageBuster, Port: 80, CreatedDate: at(10)},
+		{Kind: KindStageStarted, Stage: StageBuster, Port: 443, CreatedDate: at(11)},
+		{Kind: KindStageStarted, Stage: StageRunner, Port: 80, Tool: "nikto", CreatedDate: at(12)},
+		{Kind: KindStageFailed, Stage: StageBuster, Port: 443, Severity: SeverityError, CreatedDate: at(15)},
+		{Kind: KindStageFinished, Stage: StageBuster, Port: 80, Severity: SeveritySuccess, CreatedDate: at(40)},
+		// A stage finishing without having started is ignored
+		{Kind: KindStageFinished, Stage: StageTLS, Port: 443, Severity: SeveritySuccess, CreatedDate: at(41)},
+	}
+
+	want := []types.StageDuration{
+		{Stage: StagePortScan, Severity: SeveritySuccess, StartedDate: at(0), FinishedDate: at(10), Duration: 10},
+		{Stage: StageBuster, Port: 80, Severity: SeveritySuccess, StartedDate: at(10), FinishedDate: at(40), Duration: 30},
+		{Stage: StageBuster, Port: 443, Severity: SeverityError, StartedDate: at(11), FinishedDate: at(15), Duration: 4},
+		// Still running
+		{Stage: StageRunner, Port: 80, Tool: "nikto", Severity: SeverityInfo, StartedDate: at(12)},
+	}
+	got := Durations(evts)
+	if len(got) != len(want) {
+		t.Fatalf("got %d durations, want %d : %+v", len(got), len(want), got)
+	}
+	for idx := range want {
+		if got[idx] != want[idx] {
+			t.Errorf("duration %d is %+v, want %+v", idx, got[idx], want[idx])
+		}
+	}
+}
@@ -69,6 +69,27 @@ func FileToInts(filename string) []int {
 	return res
 }
 
+// FileToPorts : reads a ports file and returns its TCP and UDP ports,
+// lines may be prefixed by "tcp/" or "udp/", unprefixed ports are TCP
+func FileToPorts(filename string) ([]int, []int) {
+	tcp := make([]int, 0)
+	udp := make([]int, 0)
+	for _, line := range FileToStrings(filename) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "udp/") {
+			parsedInt, _ := strconv.Atoi(strings.TrimPrefix(line, "udp/"))
+			udp = append(udp, parsedInt)
+			continue
+		}
+		parsedInt, _ := strconv.Atoi(strings.TrimPrefix(line, "tcp/"))
+		tcp = append(tcp, parsedInt)
+	}
+	return tcp, udp
+}
+
 // Contains : checks if a slice contains an int
 func Contains(array []int, element int) bool {
 	for _, e := range array {
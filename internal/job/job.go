@@ -0,0 +1,371 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"FaRyuk/internal/db"
+	"FaRyuk/internal/types"
+
+	"github.com/google/uuid"
+)
+
+// Job states
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+// Job kinds
+const (
+	KindScan       = "scan"
+	KindWebScan    = "webscan"
+	KindPortScan   = "portscan"
+	KindDomainScan = "domain-scan"
+	KindSweep      = "sweep"
+	KindImport     = "import"
+)
+
+// pollInterval : how often the jobs queued by other processes are looked for
+const pollInterval = 10 * time.Second
+
+var (
+	// ErrQueueFull is returned when no more jobs can be queued
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrFinished is returned when cancelling a job that is already finished
+	ErrFinished = errors.New("job is already finished")
+)
+
+// Handler : executes a job, it should return as soon as ctx is done
+type Handler func(ctx context.Context, j *types.Job) error
+
+// Queue : bounded pool of workers executing persisted jobs
+type Queue struct {
+	workers  int
+	timeout  time.Duration
+	pending  chan string
+	handlers map[string]Handler
+	mu       sync.Mutex
+	cancels  map[string]context.CancelFunc
+	// enqueued : jobs waiting in pending
+	enqueued map[string]bool
+}
+
+// NewJob : constructs a queued job
+func NewJob(kind string, owner string, ownerGroup string, params types.JobParams) *types.Job {
+	id := uuid.New().String()
+
+	return &types.Job{
+		ID:          id,
+		Kind:        kind,
+		State:       StateQueued,
+		Params:      params,
+		Owner:       owner,
+		OwnerGroup:  ownerGroup,
+		CreatedDate: time.Now(),
+	}
+}
+
+// NewQueue : returns a new Queue with the given number of workers and capacity,
+// timeout is the deadline of jobs that do not set their own (0 means none)
+func NewQueue(workers int, size int, timeout time.Duration) *Queue {
+	if workers <= 0 {
+		workers = 1
+	}
+	if size <= 0 {
+		size = 1
+	}
+	return &Queue{
+		workers:  workers,
+		timeout:  timeout,
+		pending:  make(chan string, size),
+		handlers: make(map[string]Handler),
+		cancels:  make(map[string]context.CancelFunc),
+		enqueued: make(map[string]bool),
+	}
+}
+
+// Handle : registers the handler of a job kind
+func (q *Queue) Handle(kind string, h Handler) {
+	q.handlers[kind] = h
+}
+
+// Start : recovers jobs left over by a previous run and starts the workers
+func (q *Queue) Start() error {
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	// Running jobs were interrupted, their partial state cannot be trusted
+	running, err := dbHandler.GetJobs(StateRunning)
+	if err != nil {
+		return err
+	}
+	for idx := range running {
+		running[idx].State = StateFailed
+		running[idx].Err = "interrupted by server restart"
+		running[idx].FinishedDate = time.Now()
+		if err := dbHandler.UpdateJob(&running[idx]); err != nil {
+			return err
+		}
+	}
+
+	// Queued jobs never started and can safely be resumed
+	queued, err := dbHandler.GetJobs(StateQueued)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < q.workers; i++ {
+		go q.worker()
+	}
+
+	go func() {
+		// Oldest jobs first
+		for idx := len(queued) - 1; idx >= 0; idx-- {
+			q.mu.Lock()
+			q.enqueued[queued[idx].ID] = true
+			q.mu.Unlock()
+			q.pending <- queued[idx].ID
+		}
+		q.watch()
+	}()
+	return nil
+}
+
+// watch : queues the jobs persisted by other processes, such as the command line
+func (q *Queue) watch() {
+	for range time.Tick(pollInterval) {
+		dbHandler := db.NewDBHandler()
+		queued, err := dbHandler.GetJobs(StateQueued)
+		dbHandler.CloseConnection()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		for idx := len(queued) - 1; idx >= 0; idx-- {
+			if !q.push(queued[idx].ID) {
+				break
+			}
+		}
+	}
+}
+
+// push : queues a job unless it already is, it returns false when the queue is full
+func (q *Queue) push(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pushLocked(id)
+}
+
+// pushLocked : push with the lock held
+func (q *Queue) pushLocked(id string) bool {
+	if q.enqueued[id] {
+		return true
+	}
+	select {
+	case q.pending <- id:
+		q.enqueued[id] = true
+		return true
+	default:
+		return false
+	}
+}
+
+// Enqueue : persists a queued job out of the server, the queue of the server picks it up
+func Enqueue(j *types.Job) error {
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	j.State = StateQueued
+	return dbHandler.InsertJob(j)
+}
+
+// Submit : persists a job and queues it
+func (q *Queue) Submit(j *types.Job) error {
+	return q.SubmitAll([]*types.Job{j})
+}
+
+// SubmitAll : persists jobs and queues them, none is when the queue cannot take them all
+func (q *Queue) SubmitAll(jobs []*types.Job) error {
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if cap(q.pending)-len(q.pending) < len(jobs) {
+		return ErrQueueFull
+	}
+
+	for _, j := range jobs {
+		j.State = StateQueued
+		err := dbHandler.InsertJob(j)
+		if err != nil {
+			return err
+		}
+
+		// The jobs recovered by Start are queued without the lock and may take the room left
+		if !q.pushLocked(j.ID) {
+			j.State = StateFailed
+			j.Err = ErrQueueFull.Error()
+			j.FinishedDate = time.Now()
+			dbHandler.UpdateJob(j)
+			return ErrQueueFull
+		}
+	}
+	return nil
+}
+
+// Cancel : cancels a queued or running job and the jobs it queued, ErrFinished is only returned when none of them
+// could be cancelled
+func (q *Queue) Cancel(id string) error {
+	err := q.cancel(id)
+	if err != nil && !errors.Is(err, ErrFinished) {
+		return err
+	}
+	cancelled, childErr := q.cancelChildren(id)
+	if childErr != nil {
+		return childErr
+	}
+	if cancelled {
+		return nil
+	}
+	return err
+}
+
+// cancelChildren : cancels the jobs queued by a job, it tells whether any of them was
+func (q *Queue) cancelChildren(id string) (bool, error) {
+	dbHandler := db.NewDBHandler()
+	children, err := dbHandler.GetJobsByParent(id)
+	dbHandler.CloseConnection()
+	if err != nil {
+		return false, err
+	}
+
+	cancelled := false
+	for _, child := range children {
+		err = q.Cancel(child.ID)
+		if err == nil {
+			cancelled = true
+		} else if !errors.Is(err, ErrFinished) {
+			return cancelled, err
+		}
+	}
+	return cancelled, nil
+}
+
+// cancel : cancels a queued or running job
+func (q *Queue) cancel(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if cancel, ok := q.cancels[id]; ok {
+		// The worker sets the final state once the handler returned
+		cancel()
+		return nil
+	}
+
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	j, err := dbHandler.GetJobByID(id)
+	if err != nil {
+		return err
+	}
+	if j.State != StateQueued {
+		return ErrFinished
+	}
+	j.State = StateCancelled
+	j.FinishedDate = time.Now()
+	return dbHandler.UpdateJob(&j)
+}
+
+func (q *Queue) worker() {
+	for id := range q.pending {
+		q.run(id)
+	}
+}
+
+// start : marks a job as running, returns nil if it must be skipped
+func (q *Queue) start(dbHandler *db.Handler, id string) (*types.Job, context.Context) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.enqueued, id)
+
+	j, err := dbHandler.GetJobByID(id)
+	if err != nil || j.State != StateQueued {
+		return nil, nil
+	}
+
+	timeout := q.timeout
+	if j.Params.Timeout > 0 {
+		timeout = time.Duration(j.Params.Timeout) * time.Second
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	q.cancels[id] = cancel
+
+	j.State = StateRunning
+	j.StartedDate = time.Now()
+	dbHandler.UpdateJob(&j)
+	return &j, ctx
+}
+
+func (q *Queue) run(id string) {
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	j, ctx := q.start(dbHandler, id)
+	if j == nil {
+		return
+	}
+
+	var err error
+	h, ok := q.handlers[j.Kind]
+	if !ok {
+		err = fmt.Errorf("unknown job kind %s", j.Kind)
+	} else {
+		err = h(ctx, j)
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		j.State = StateCancelled
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		j.State = StateFailed
+		j.Err = "deadline exceeded"
+	case err != nil:
+		j.State = StateFailed
+		j.Err = err.Error()
+	default:
+		j.State = StateSucceeded
+	}
+
+	q.mu.Lock()
+	q.cancels[id]()
+	delete(q.cancels, id)
+	q.mu.Unlock()
+
+	j.FinishedDate = time.Now()
+	if err := dbHandler.UpdateJob(j); err != nil {
+		log.Println(err)
+	}
+
+	// Jobs queued by the handler while it was being cancelled
+	if j.State == StateCancelled {
+		if _, err := q.cancelChildren(j.ID); err != nil {
+			log.Println(err)
+		}
+	}
+}
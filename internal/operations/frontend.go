@@ -1,12 +1,18 @@
 package operations
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"FaRyuk/config"
+	"FaRyuk/internal/blob"
 	"FaRyuk/internal/db"
+	"FaRyuk/internal/events"
 	"FaRyuk/internal/helper"
+	"FaRyuk/internal/screenshot"
 	"FaRyuk/internal/types"
 	"FaRyuk/pkg"
 
@@ -15,6 +21,7 @@ import (
 
 // DoHost : launchs scan on host
 func DoHost(
+	ctx context.Context,
 	idUser string,
 	host string,
 	groupId string,
@@ -25,7 +32,7 @@ func DoHost(
 	var result types.Result
 	var runners []types.Runner
 	var webRunners []types.Runner
-	ports := helper.FileToInts("./ressources/ports/" + portsFilename)
+	ports, udpPorts := helper.FileToPorts("./ressources/ports/" + portsFilename)
 	dirs := helper.FileToStrings("./ressources/dirs/" + dirsFilename)
 
 	dbHandler := db.NewDBHandler()
@@ -52,47 +59,88 @@ func DoHost(
 		OwnerGroup:  groupId,
 		CreatedDate: time.Now(),
 	}
-	historyRecord.State = append(historyRecord.State, "[*] Scan started", "[*] Portlist : "+portsFilename, "[*] Wordlist : "+dirsFilename)
 	err := dbHandler.InsertHistoryRecord(historyRecord)
 	if err != nil {
 		return result, fmt.Errorf("could not create history record")
 	}
 
-	historyUpdater := func(statement string) {
-		historyRecord.State = append(historyRecord.State, statement)
-		dbHandler.UpdateHistoryRecord(historyRecord)
-	}
+	rec := events.NewRecorder(dbHandler, historyRecord.ID)
+	rec.Info("Scan started")
+	rec.Info("Portlist : " + portsFilename)
+	rec.Info("Wordlist : " + dirsFilename)
+
 	// Resolve domain
+	rec.Started(events.StageResolution, 0, "")
 	resolver := pkg.NewResolver()
-	resolutions := resolver.Resolve(host)
+	resolutions := resolver.Resolve(ctx, host)
 
 	if len(resolutions) == 0 {
-		historyUpdater("[-] Resolution failed")
-		historyRecord.IsFinished = true
-		historyRecord.IsSuccess = false
-		historyUpdater("[-] Scan failed")
-		return result, fmt.Errorf("resolutions failed")
+		err = fmt.Errorf("resolutions failed")
+		rec.Failed(events.StageResolution, 0, "", err)
+		rec.Finish(ctx, err)
+		return result, err
 	}
-
-	historyUpdater("[+] Resolution finished")
+	rec.Finished(events.StageResolution, 0, "", len(resolutions))
 
 	// Scan ports
+	rec.Started(events.StagePortScan, 0, "")
 	portscanner := pkg.NewPortScanner(host, 2*time.Second, 5)
-	openPorts := portscanner.Run(ports)
-	historyUpdater("[+] Port scanning finished : " + fmt.Sprintf("%v", openPorts))
+	openPorts := portscanner.Run(ctx, ports)
+	if ctx.Err() != nil {
+		rec.Finish(ctx, ctx.Err())
+		return result, ctx.Err()
+	}
+	rec.Emit(types.ScanEvent{
+		Kind:  events.KindStageFinished,
+		Stage: events.StagePortScan,
+		Ports: openPorts,
+		Count: len(openPorts),
+	})
+
+	openUDPPorts := make([]pkg.UDPPort, 0)
+	if len(udpPorts) > 0 {
+		rec.Started(events.StageUDPScan, 0, "")
+		openUDPPorts = portscanner.RunUDP(ctx, udpPorts)
+		if ctx.Err() != nil {
+			rec.Finish(ctx, ctx.Err())
+			return result, ctx.Err()
+		}
+		found := make([]int, 0, len(openUDPPorts))
+		for _, p := range openUDPPorts {
+			found = append(found, p.Port)
+		}
+		rec.Emit(types.ScanEvent{
+			Kind:  events.KindStageFinished,
+			Stage: events.StageUDPScan,
+			Ports: found,
+			Count: len(found),
+		})
+	}
+
+	// Detect services
+	rec.Started(events.StageServices, 0, "")
+	detector := pkg.NewServiceDetector(host, 3*time.Second, 5)
+	services := detector.Run(ctx, openPorts)
+	if ctx.Err() != nil {
+		rec.Finish(ctx, ctx.Err())
+		return result, ctx.Err()
+	}
+	rec.Finished(events.StageServices, 0, "", len(services))
 
 	result = types.Result{
-		ID:          uuid.New().String(),
-		Owner:       idUser,
-		Host:        host,
-		Ips:         resolutions,
-		OpenPorts:   openPorts,
-		OwnerGroup:  groupId,
-		CreatedDate: time.Now(),
+		ID:           uuid.New().String(),
+		Owner:        idUser,
+		Host:         host,
+		Ips:          resolutions,
+		OpenPorts:    openPorts,
+		OpenUDPPorts: openUDPPorts,
+		Services:     services,
+		OwnerGroup:   groupId,
+		CreatedDate:  time.Now(),
 	}
 
 	for idx := range runners {
-		r, err := launchRunner(host, 0, "", runners[idx])
+		r, err := launchRunner(ctx, rec, host, 0, "", runners[idx])
 		if err != nil {
 			result.Err = append(result.Err, fmt.Sprintf("%s", err))
 		} else {
@@ -100,27 +148,43 @@ func DoHost(
 		}
 	}
 
-	for _, port := range result.OpenPorts {
-		isWeb, isSSL := fingerprintPort(host, port)
+	busterParams := types.BusterParams{
+		Threads: config.Cfg.Buster.Threads,
+		Rate:    config.Cfg.Buster.Rate,
+	}
+	for _, service := range result.Services {
+		if ctx.Err() != nil {
+			break
+		}
+		port := service.Port
+		isWeb, isSSL := fingerprintPort(ctx, host, service)
 		if isWeb {
-			webresult, _ := getWebResult(idUser, host, port, isSSL, "", dirs, "", false, "", historyRecord.ID, webRunners)
+			webresult, _ := getWebResult(ctx, rec, host, port, isSSL, "", dirs, "", false, "", busterParams, webRunners)
 			result.WebResults = append(result.WebResults, webresult)
+			for _, tag := range techTags(webresult) {
+				if !helper.ContainsStr(result.Tags, tag) {
+					result.Tags = append(result.Tags, tag)
+				}
+			}
+			for _, candidate := range webresult.TLS.CandidateHosts(host) {
+				if !helper.ContainsStr(result.Candidates, candidate) {
+					result.Candidates = append(result.Candidates, candidate)
+				}
+			}
 		}
 	}
 
+	result.Security = SecuritySummary(result.WebResults)
 	if !helper.ContainsStr(result.Tags, "#new") {
 		result.Tags = append(result.Tags, "#new")
 	}
 
-	historyRecord, _ = dbHandler.GetHistoryRecordByID(historyRecord.ID)
-	historyRecord.IsFinished = true
-	historyRecord.IsSuccess = true
-	historyUpdater("[+] Scan finished")
-	return result, nil
+	rec.Finish(ctx, nil)
+	return result, ctx.Err()
 }
 
 // WebScanPort : launches a webscan of a host in a given port
-func WebScanPort(idUser, id string, port int, ssl bool, base, dirFilename, statusCodes string, wildcardForced bool, excludedText string, scanners []string) bool {
+func WebScanPort(ctx context.Context, idUser, id string, port int, ssl bool, base, dirFilename, statusCodes string, wildcardForced bool, excludedText string, busterParams types.BusterParams, scanners []string) error {
 	var res types.Result
 	var webRunners []types.Runner
 	dirs := helper.FileToStrings("./ressources/dirs/" + dirFilename)
@@ -139,11 +203,35 @@ func WebScanPort(idUser, id string, port int, ssl bool, base, dirFilename, statu
 
 	resPtr := dbHandler.GetResultByID(id)
 	if resPtr == nil {
-		return false
+		return fmt.Errorf("no result with such id")
 	}
 	res = *resPtr
 	res.Owner = idUser
-	webresult, _ := getWebResult(idUser, res.Host, port, ssl, base, dirs, statusCodes, wildcardForced, excludedText, "-"+dirFilename, webRunners)
+
+	historyRecord := types.HistoryRecord{
+		ID:          uuid.New().String(),
+		Owner:       idUser,
+		IsWeb:       true,
+		IsFinished:  false,
+		Host:        res.Host,
+		CreatedDate: time.Now(),
+	}
+	err := dbHandler.InsertHistoryRecord(historyRecord)
+	if err != nil {
+		return err
+	}
+
+	rec := events.NewRecorder(dbHandler, historyRecord.ID)
+	rec.Info("Scan started")
+	rec.Info(fmt.Sprintf("Port : %d", port))
+	rec.Info("Base : " + base)
+	rec.Info("Wordlist : " + dirFilename)
+
+	webresult, _ := getWebResult(ctx, rec, res.Host, port, ssl, base, dirs, statusCodes, wildcardForced, excludedText, busterParams, webRunners)
+	rec.Finish(ctx, nil)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	webresult.CreatedDate = time.Now()
 	l := sync.Mutex{}
 	l.Lock()
@@ -166,28 +254,50 @@ func WebScanPort(idUser, id string, port int, ssl bool, base, dirFilename, statu
 				res.WebResults[idx].Busterres = append(res.WebResults[idx].Busterres, busterres)
 			}
 		}
+		if res.WebResults[idx].Screen.ID != webresult.Screen.ID {
+			screenshot.Remove(res.WebResults[idx].Screen.ID)
+		}
+		res.WebResults[idx].Screen = webresult.Screen
+		res.WebResults[idx].Baseline = webresult.Baseline
+		res.WebResults[idx].Headers = webresult.Headers
+		res.WebResults[idx].Audit = webresult.Audit
+		res.WebResults[idx].TLS = webresult.TLS
+		res.WebResults[idx].Technologies = webresult.Technologies
 		res.WebResults[idx].Err = append(res.WebResults[idx].Err, webresult.Err...)
 		res.WebResults[idx].RunnerOutput = webresult.RunnerOutput
 	}
 	if !exists {
 		res.WebResults = append(res.WebResults, webresult)
 	}
+	res.Security = SecuritySummary(res.WebResults)
 
+	for _, tag := range techTags(webresult) {
+		if !helper.ContainsStr(res.Tags, tag) {
+			res.Tags = append(res.Tags, tag)
+		}
+	}
 	if !helper.ContainsStr(res.Tags, "#new") {
 		res.Tags = append(res.Tags, "#new")
 	}
 
 	retval := dbHandler.UpdateResult(&res)
 	l.Unlock()
-	return retval
+	if !retval {
+		return fmt.Errorf("could not update result")
+	}
+	return nil
 }
 
-func RunnerScanPort(idUser, id string, port int, scanners []string) bool {
+// RunnerScanPort : launches port runners on a port of an existing result
+func RunnerScanPort(ctx context.Context, idUser, id string, port int, scanners []string) error {
 	var portRunners []types.Runner
 	var historyRecord types.HistoryRecord
 	dbHandler := db.NewDBHandler()
 	defer dbHandler.CloseConnection()
 	resPtr := dbHandler.GetResultByID(id)
+	if resPtr == nil {
+		return fmt.Errorf("no result with such id")
+	}
 	result := *resPtr
 
 	historyRecord.ID = uuid.New().String()
@@ -196,13 +306,15 @@ func RunnerScanPort(idUser, id string, port int, scanners []string) bool {
 	historyRecord.IsFinished = false
 	historyRecord.Host = result.Host
 	historyRecord.OwnerGroup = result.OwnerGroup
-	historyRecord.State = append(historyRecord.State, "[*] Scan started")
 	historyRecord.CreatedDate = time.Now()
 	err := dbHandler.InsertHistoryRecord(historyRecord)
 	if err != nil {
-		return false
+		return err
 	}
 
+	rec := events.NewRecorder(dbHandler, historyRecord.ID)
+	rec.Info("Scan started")
+
 	for idx := range scanners {
 		r, err := dbHandler.GetRunnerByID(scanners[idx])
 		if err != nil {
@@ -214,10 +326,10 @@ func RunnerScanPort(idUser, id string, port int, scanners []string) bool {
 	}
 
 	for idx := range portRunners {
-		historyRecord.State = append(historyRecord.State,
-			"[*] "+portRunners[idx].DisplayName+" started for port "+fmt.Sprintf("%d", port))
-		dbHandler.UpdateHistoryRecord(historyRecord)
-		r, err := launchRunner(result.Host, port, "", portRunners[idx])
+		if ctx.Err() != nil {
+			break
+		}
+		r, err := launchRunner(ctx, rec, result.Host, port, "", portRunners[idx])
 		if err == nil {
 			exists := false
 			for idx := range result.RunnerOutput {
@@ -229,14 +341,9 @@ func RunnerScanPort(idUser, id string, port int, scanners []string) bool {
 			if !exists {
 				result.RunnerOutput = append(result.RunnerOutput, r)
 			}
-			historyRecord.State = append(historyRecord.State,
-				"[+] "+portRunners[idx].DisplayName+" finished for port "+fmt.Sprintf("%d", port))
 		} else {
-			historyRecord.State = append(historyRecord.State,
-				"[-] "+portRunners[idx].DisplayName+" failed for port "+fmt.Sprintf("%d", port))
 			result.Err = append(result.Err, fmt.Sprintf("%s", err))
 		}
-		dbHandler.UpdateHistoryRecord(historyRecord)
 	}
 
 	if !helper.ContainsStr(result.Tags, "#new") {
@@ -244,19 +351,34 @@ func RunnerScanPort(idUser, id string, port int, scanners []string) bool {
 	}
 	retval := dbHandler.UpdateResult(&result)
 
-	historyRecord.IsFinished = true
-	historyRecord.IsSuccess = true
-	historyRecord.State = append(historyRecord.State, "[+] Scan finished")
-	dbHandler.UpdateHistoryRecord(historyRecord)
+	rec.Finish(ctx, nil)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 
-	return retval
+	if !retval {
+		return fmt.Errorf("could not update result")
+	}
+	return nil
 }
 
-// DoDomain : launch gobuster on domain
-func DoDomain(idUser, domain, groupId, subdomainFilename string, isWildcard bool, resolver string) ([]string, error) {
+// DoDomain : launches gobuster on domain and returns the subdomains found. The words of subdomainFilename are brute
+// forced by threads workers, and isWildcard forces the brute force of a wildcard domain. The lookups go through the
+// given resolvers, each sent at most rate queries per second, or through the resolvers of the server and their own
+// limit when there are none. Resume continues the brute force of an interrupted scan of the domain from where it
+// stopped, if that scan used the same wordlist. Permutations is the maximum number of names derived from the
+// subdomains found which are resolved once the brute force is done, 0 disables them
+func DoDomain(
+	ctx context.Context,
+	idUser, domain, groupId, subdomainFilename string,
+	isWildcard bool,
+	resolvers []string,
+	threads, rate int,
+	resume bool,
+	permutations int,
+) ([]string, error) {
 	var historyRecord types.HistoryRecord
 	dirs := helper.FileToStrings("./ressources/subdomains/" + subdomainFilename)
-	chunks := helper.ChunkSlice(dirs, len(dirs)/9)
 	results := make([]string, 0)
 
 	dbHandler := db.NewDBHandler()
@@ -268,30 +390,254 @@ func DoDomain(idUser, domain, groupId, subdomainFilename string, isWildcard bool
 	historyRecord.IsWeb = false
 	historyRecord.IsFinished = false
 	historyRecord.Domain = domain
-	historyRecord.State = append(historyRecord.State, "[*] Scan started", "[*] DNS list : "+subdomainFilename)
 	historyRecord.CreatedDate = time.Now()
 	err := dbHandler.InsertHistoryRecord(historyRecord)
 	if err != nil {
 		return results, fmt.Errorf("could not create history record")
 	}
 
-	for idx, slice := range chunks {
-		r, err := launchBusterDNS(domain, slice, isWildcard, resolver)
+	rec := events.NewRecorder(dbHandler, historyRecord.ID)
+	rec.Info("Scan started")
+	rec.Info("DNS list : " + subdomainFilename)
+
+	// Lookups are shared by the resolvers of the scan, by the ones of the server when none is given
+	pool := pkg.DefaultResolverPool()
+	server := ""
+	if len(resolvers) > 0 {
+		pool = pkg.NewResolverPool(resolvers, config.Cfg.DNS.Trusted, 2*time.Second, rate)
+		server = resolvers[0]
+	}
+	rec.Info("Resolvers : " + strings.Join(pool.Addresses(), ", "))
+
+	// Zone transfers give the subdomains without brute force when a nameserver allows them
+	client, clientErr := pkg.NewDNSClient(server, 2*time.Second)
+	transferred := make([]string, 0)
+	findings := make([]types.DomainFinding, 0)
+	if clientErr != nil {
+		rec.Failed(events.StageAXFR, 0, "", clientErr)
+	} else {
+		transferred, findings = transferZone(ctx, rec, client, domain)
+	}
+	if ctx.Err() != nil {
+		rec.Finish(ctx, ctx.Err())
+		return make([]string, 0), ctx.Err()
+	}
+
+	// The progress of the brute force is kept in the domain result
+	rec.Started(events.StageDNS, 0, "")
+	domainResult, err := getDomainResult(dbHandler, idUser, groupId, domain)
+	if err != nil {
+		rec.Failed(events.StageDNS, 0, "", err)
+		rec.Finish(ctx, err)
+		return results, err
+	}
+	state := domainResult.BruteForce
+	switch {
+	case resume && state != nil && !state.Finished && state.Wordlist == subdomainFilename && state.Total == len(dirs):
+		rec.Info(fmt.Sprintf("Resuming at word %d of %d, %d subdomains already found", state.Offset, len(dirs), len(state.Found)))
+	default:
+		if resume {
+			rec.Info("No interrupted scan with this DNS list to resume")
+		}
+		state = &types.BruteForceState{Wordlist: subdomainFilename, Total: len(dirs), Found: make([]string, 0)}
+	}
+
+	err = launchBusterDNS(ctx, rec, dbHandler, domainResult.ID, state, domain, dirs, isWildcard, pool, threads)
+	if ctx.Err() != nil {
+		rec.Finish(ctx, ctx.Err())
+		return results, ctx.Err()
+	}
+	// The names transferred and the words found before a failure are still looked up and saved
+	if err != nil {
+		rec.Failed(events.StageDNS, 0, "", err)
+	} else {
+		rec.Finished(events.StageDNS, 0, "", len(state.Found))
+	}
+	results = append(results, state.Found...)
+
+	sources := make(map[string]string)
+	for _, word := range results {
+		sources[word] = types.SourceBruteForce
+	}
+	for _, word := range transferred {
+		if sources[word] == "" {
+			sources[word] = types.SourceZoneTransfer
+			results = append(results, word)
+		}
+	}
+
+	// Second pass on names derived from the subdomains found
+	if permutations > 0 {
+		rec.Started(events.StagePermute, 0, "")
+		permuted, err := launchPermutations(ctx, domain, results, permutations, isWildcard, pool, threads)
+		if ctx.Err() != nil {
+			rec.Finish(ctx, ctx.Err())
+			return results, ctx.Err()
+		}
 		if err != nil {
-			historyRecord.State = append(historyRecord.State, "[-] Scan failed : "+fmt.Sprintf("%s", err))
-			historyRecord.IsFinished = true
-			historyRecord.IsSuccess = false
-			dbHandler.UpdateHistoryRecord(historyRecord)
-			return make([]string, 0), err
+			rec.Failed(events.StagePermute, 0, "", err)
+		} else {
+			for _, word := range permuted {
+				sources[word] = types.SourcePermutation
+				results = append(results, word)
+			}
+			rec.Finished(events.StagePermute, 0, "", len(permuted))
 		}
-		results = append(results, r...)
-		historyRecord.State = append(historyRecord.State, fmt.Sprintf("[*] : %%%d done, found : %d", (idx+1)*10, len(r)))
-		dbHandler.UpdateHistoryRecord(historyRecord)
 	}
 
-	historyRecord.State = append(historyRecord.State, fmt.Sprintf("[+] Scan finished : Found %d", len(results)))
-	historyRecord.IsFinished = true
-	historyRecord.IsSuccess = true
-	dbHandler.UpdateHistoryRecord(historyRecord)
+	// The stats of the resolvers of the server are shared by every scan
+	if len(resolvers) > 0 {
+		for _, s := range pool.Stats() {
+			message := fmt.Sprintf("Resolver %s : %d queries, %d errors, %d poisoned answers, %dms on average",
+				s.Address, s.Queries, s.Errors, s.Poisoned, s.AverageLatency)
+			severity := events.SeverityInfo
+			if s.Excluded {
+				message += ", excluded : " + s.Reason
+				severity = events.SeverityWarning
+			}
+			rec.Emit(types.ScanEvent{Kind: events.KindInfo, Stage: events.StageDNS, Severity: severity, Message: message})
+		}
+	}
+
+	// DNS records of the domain and of the subdomains found, the queries share the resolvers of the scan and their rate
+	rec.Started(events.StageRecords, 0, "")
+	names := make([]string, len(results))
+	for idx, word := range results {
+		names[idx] = word + "." + domain
+	}
+	records, err := pool.Lookup(ctx, domain, true)
+	if err != nil && ctx.Err() == nil {
+		rec.Info(fmt.Sprintf("Could not get the records of %s : %s", domain, err))
+	}
+	subdomains := lookupSubdomains(ctx, pool, names)
+	rec.Finished(events.StageRecords, 0, "", len(subdomains))
+	for idx := range subdomains {
+		subdomains[idx].Source = sources[results[idx]]
+	}
+
+	// Dangling CNAME records of the subdomains found and of the ones found before or imported
+	checked := make([]string, 0)
+	if clientErr == nil {
+		candidates := append(make([]types.Subdomain, 0, len(subdomains)), subdomains...)
+		for _, sub := range subdomains {
+			checked = append(checked, sub.Name)
+		}
+		for _, sub := range domainResult.Subdomains {
+			if sources[strings.TrimSuffix(sub.Name, "."+domain)] == "" {
+				candidates = append(candidates, sub)
+				checked = append(checked, sub.Name)
+			}
+		}
+
+		rec.Started(events.StageTakeover, 0, "")
+		takeovers := checkTakeovers(ctx, pkg.NewTakeoverChecker(client, 10*time.Second), candidates)
+		if ctx.Err() != nil {
+			rec.Finish(ctx, ctx.Err())
+			return results, ctx.Err()
+		}
+		for _, f := range takeovers {
+			rec.Emit(types.ScanEvent{Kind: events.KindInfo, Stage: events.StageTakeover, Severity: events.SeverityWarning, Message: f.Message})
+		}
+		rec.Finished(events.StageTakeover, 0, "", len(takeovers))
+		findings = append(findings, takeovers...)
+	}
+
+	err = saveDomainResult(dbHandler, idUser, groupId, domain, records, subdomains, findings, checked)
+	if err != nil {
+		rec.Failed(events.StageRecords, 0, "", err)
+	}
+
+	rec.Finish(ctx, nil)
 	return results, nil
 }
+
+// ImportSubdomains : adds the subdomains of a dataset to the domain result of a user. Subdomains already in the
+// result are left out. When resolve is set, the subdomains are looked up through the resolver pool of the server and
+// the ones which do not resolve are left out as well. The summary lists the subdomains imported
+func ImportSubdomains(ctx context.Context, idUser, groupID, domain string, names []string, resolve bool) (*types.ImportSummary, error) {
+	dbHandler := db.NewDBHandler()
+	defer dbHandler.CloseConnection()
+
+	result, err := getDomainResult(dbHandler, idUser, groupID, domain)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool)
+	for _, sub := range result.Subdomains {
+		known[sub.Name] = true
+	}
+
+	summary := &types.ImportSummary{Domain: domain, Total: len(names), Imported: make([]string, 0)}
+	fresh := make([]string, 0)
+	for _, name := range names {
+		if known[name] {
+			summary.Duplicates++
+		} else {
+			fresh = append(fresh, name)
+		}
+	}
+
+	subdomains := make([]types.Subdomain, 0)
+	if resolve {
+		resolved := resolveSubdomains(ctx, pkg.DefaultResolverPool(), fresh)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		for _, sub := range resolved {
+			if len(sub.IPs) == 0 {
+				summary.Unresolved++
+				continue
+			}
+			sub.Source = types.SourceImport
+			subdomains = append(subdomains, sub)
+		}
+	} else {
+		for _, name := range fresh {
+			subdomains = append(subdomains, types.Subdomain{Name: name, Records: make([]pkg.DNSRecord, 0), IPs: make([]string, 0), Source: types.SourceImport})
+		}
+	}
+
+	for _, sub := range subdomains {
+		summary.Imported = append(summary.Imported, sub.Name)
+	}
+	if len(subdomains) == 0 {
+		return summary, nil
+	}
+	result.UpdatedDate = time.Now()
+	mergeSubdomains(result, subdomains, result.UpdatedDate)
+	return summary, dbHandler.UpdateDomainResult(result)
+}
+
+// importBucket : bucket of the blob store holding the datasets waiting for an import job
+const importBucket = "imports"
+
+// StoreImportDataset : keeps the subdomains of a dataset in the blob store until a job imports them, it returns the
+// ID of the dataset
+func StoreImportDataset(names []string) (string, error) {
+	store, err := blob.NewStore(importBucket)
+	if err != nil {
+		return "", err
+	}
+	id := uuid.New().String()
+	return id, store.Put(id, []byte(strings.Join(names, "\n")))
+}
+
+// ImportStoredSubdomains : imports with resolution the subdomains of a dataset kept by StoreImportDataset, the
+// dataset is removed once imported
+func ImportStoredSubdomains(ctx context.Context, idUser, groupID, domain, datasetID string) (*types.ImportSummary, error) {
+	store, err := blob.NewStore(importBucket)
+	if err != nil {
+		return nil, err
+	}
+	data, err := store.Get(datasetID)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Remove(datasetID)
+
+	names := make([]string, 0)
+	if len(data) > 0 {
+		names = strings.Split(string(data), "\n")
+	}
+	return ImportSubdomains(ctx, idUser, groupID, domain, names, true)
+}
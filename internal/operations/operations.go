@@ -3,49 +3,382 @@ package operations
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"FaRyuk/config"
 	"FaRyuk/internal/db"
+	"FaRyuk/internal/events"
 	"FaRyuk/internal/runner"
+	"FaRyuk/internal/screenshot"
 	"FaRyuk/internal/types"
 	"FaRyuk/pkg"
 
 	"github.com/google/uuid"
 )
 
+// tagRegexp : characters that cannot be part of a tag
+var tagRegexp = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// newBusterDNS : returns a GobusterDNS for a domain once its wildcard responses are known, it finds the names with a
+// dangling CNAME record too
+func newBusterDNS(
+	ctx context.Context,
+	domain string,
+	wildCardForced bool,
+	pool *pkg.ResolverPool,
+	threads int,
+) (*pkg.GobusterDNS, error) {
+	opts := pkg.NewOptionsDNS(domain, wildCardForced, pool)
+	opts.Dangling = true
+
+	// Jobs queued before the threads were configurable have none
+	if threads <= 0 {
+		threads = config.Cfg.DNS.Threads
+	}
+	opts.Threads = threads
+
+	busterdns, err := pkg.NewGobusterDNS(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = busterdns.PreRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return busterdns, nil
+}
+
+// dnsBatchSize : words brute forced between two saves of the progress
+const dnsBatchSize = 1000
+
+// launchBusterDNS : brute forces the subdomains of a domain from the offset of state, the progress and the words found
+// are saved in the domain result after every batch of words
 func launchBusterDNS(
+	ctx context.Context,
+	rec *events.Recorder,
+	dbHandler *db.Handler,
+	resultID string,
+	state *types.BruteForceState,
 	domain string,
 	dirs []string,
 	wildCardForced bool,
-	resolver string,
-) ([]string, error) {
-	// Scan subdomains
-	opts := pkg.NewOptionsDNS(domain, wildCardForced, resolver)
+	pool *pkg.ResolverPool,
+	threads int,
+) error {
+	busterdns, err := newBusterDNS(ctx, domain, wildCardForced, pool, threads)
+	if err != nil {
+		return err
+	}
 
-	opts.Timeout = 1 * time.Second
+	found := make(map[string]bool)
+	for _, word := range state.Found {
+		found[word] = true
+	}
+	for state.Offset < len(dirs) {
+		end := state.Offset + dnsBatchSize
+		if end > len(dirs) {
+			end = len(dirs)
+		}
+		r := busterdns.Run(ctx, dirs[state.Offset:end])
+		for _, word := range r {
+			if !found[word] {
+				found[word] = true
+				state.Found = append(state.Found, word)
+			}
+		}
+		// An interrupted batch is processed again when resuming
+		if ctx.Err() == nil {
+			state.Offset = end
+		}
+		state.UpdatedDate = time.Now()
+		err = dbHandler.UpdateDomainResultBruteForce(resultID, state)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 
-	busterdns, err := pkg.NewGobusterDNS(opts)
+		rec.Emit(types.ScanEvent{
+			Kind:    events.KindProgress,
+			Stage:   events.StageDNS,
+			Count:   len(r),
+			Message: fmt.Sprintf("%%%d done", state.Offset*100/len(dirs)),
+		})
+	}
+
+	state.Finished = true
+	state.UpdatedDate = time.Now()
+	return dbHandler.UpdateDomainResultBruteForce(resultID, state)
+}
+
+// launchPermutations : resolves at most max permutations of the subdomains found, it returns the ones which exist
+func launchPermutations(
+	ctx context.Context,
+	domain string,
+	known []string,
+	max int,
+	wildCardForced bool,
+	pool *pkg.ResolverPool,
+	threads int,
+) ([]string, error) {
+	if max > config.Cfg.DNS.MaxPermutations {
+		max = config.Cfg.DNS.MaxPermutations
+	}
+	candidates := pkg.Permutations(known, max)
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+
+	busterdns, err := newBusterDNS(ctx, domain, wildCardForced, pool, threads)
 	if err != nil {
 		return nil, err
 	}
+	return busterdns.Run(ctx, candidates), nil
+}
+
+// lookupSubdomains : collects the DNS records of subdomains through a resolver pool, 10 at a time
+func lookupSubdomains(ctx context.Context, pool *pkg.ResolverPool, names []string) []types.Subdomain {
+	subdomains := make([]types.Subdomain, len(names))
+	var wg sync.WaitGroup
+	sem := make(chan bool, 10)
+	for idx, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- true
+		wg.Add(1)
+		go func(idx int, name string) {
+			defer wg.Done()
+			records, _ := pool.Lookup(ctx, name, false)
+			ips := append(make([]string, 0), pkg.RecordValues(records, "A")...)
+			ips = append(ips, pkg.RecordValues(records, "AAAA")...)
+			subdomains[idx] = types.Subdomain{Name: name, Records: records, IPs: ips}
+			<-sem
+		}(idx, name)
+	}
+	wg.Wait()
+	return subdomains
+}
+
+// resolveSubdomains : looks up the addresses of names through a resolver pool, the subdomains of the names which do
+// not resolve have no IP
+func resolveSubdomains(ctx context.Context, pool *pkg.ResolverPool, names []string) []types.Subdomain {
+	subdomains := make([]types.Subdomain, len(names))
+	var wg sync.WaitGroup
+	sem := make(chan bool, 10)
+	for idx, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- true
+		wg.Add(1)
+		go func(idx int, name string) {
+			defer wg.Done()
+			ips, err := pool.LookupHost(ctx, name)
+			if err != nil {
+				ips = make([]string, 0)
+			}
+			subdomains[idx] = types.Subdomain{Name: name, Records: make([]pkg.DNSRecord, 0), IPs: ips}
+			<-sem
+		}(idx, name)
+	}
+	wg.Wait()
+	return subdomains
+}
 
-	err = busterdns.PreRun()
+// transferZone : attempts zone transfers of a domain, returns the subdomains transferred relative to the domain
+// and a finding for each nameserver allowing them. The findings keep the number of records transferred and their
+// names, not the records themselves
+func transferZone(ctx context.Context, rec *events.Recorder, client *pkg.DNSClient, domain string) ([]string, []types.DomainFinding) {
+	words := make([]string, 0)
+	findings := make([]types.DomainFinding, 0)
+
+	rec.Started(events.StageAXFR, 0, "")
+	transfers, err := client.TransferZone(ctx, domain)
 	if err != nil {
-		return nil, err
+		rec.Failed(events.StageAXFR, 0, "", err)
+		return words, findings
 	}
 
-	return busterdns.Run(dirs), nil
+	suffix := "." + strings.ToLower(domain)
+	seen := make(map[string]bool)
+	for idx := range transfers {
+		t := transfers[idx]
+		if !t.Success {
+			rec.Info(fmt.Sprintf("Zone transfer refused by %s %s : %s", t.Nameserver, t.Address, t.Err))
+			continue
+		}
+		message := fmt.Sprintf("%s (%s) allows zone transfers of %s, %d records", t.Nameserver, t.Address, domain, len(t.Records))
+		rec.Emit(types.ScanEvent{Kind: events.KindInfo, Stage: events.StageAXFR, Severity: events.SeverityWarning, Message: message})
+
+		transfer := &types.TransferFinding{Nameserver: t.Nameserver, Address: t.Address, Records: len(t.Records), Names: make([]string, 0)}
+		names := make(map[string]bool)
+		for _, r := range t.Records {
+			name := strings.ToLower(r.Name)
+			if !names[name] {
+				names[name] = true
+				transfer.Names = append(transfer.Names, name)
+			}
+			if !strings.HasSuffix(name, suffix) || strings.Contains(name, "*") {
+				continue
+			}
+			word := strings.TrimSuffix(name, suffix)
+			if !seen[word] {
+				seen[word] = true
+				words = append(words, word)
+			}
+		}
+		findings = append(findings, types.DomainFinding{
+			Kind:        types.FindingAXFR,
+			Severity:    pkg.SeverityHigh,
+			Subdomain:   domain,
+			Message:     message,
+			Transfer:    transfer,
+			CreatedDate: time.Now(),
+		})
+	}
+	rec.Finished(events.StageAXFR, 0, "", len(words))
+	return words, findings
+}
+
+// checkTakeovers : returns the takeover findings of subdomains
+func checkTakeovers(ctx context.Context, checker *pkg.TakeoverChecker, subdomains []types.Subdomain) []types.DomainFinding {
+	findings := make([]types.DomainFinding, 0)
+	for _, sub := range subdomains {
+		if ctx.Err() != nil {
+			break
+		}
+		takeover := checker.Check(ctx, sub.Name, sub.Records)
+		if takeover == nil {
+			continue
+		}
+		findings = append(findings, types.DomainFinding{
+			Kind:        types.FindingTakeover,
+			Severity:    pkg.SeverityHigh,
+			Subdomain:   sub.Name,
+			Message:     fmt.Sprintf("%s points to an unclaimed %s resource %s", sub.Name, takeover.Service, takeover.CNAME),
+			Takeover:    takeover,
+			CreatedDate: time.Now(),
+		})
+	}
+	return findings
+}
+
+// getDomainResult : returns the domain result of a domain scanned by a user, it is created if it does not exist
+func getDomainResult(dbHandler *db.Handler, idUser, groupID, domain string) (*types.DomainResult, error) {
+	result := dbHandler.GetDomainResultByDomainAndOwner(domain, idUser)
+	if result != nil {
+		return result, nil
+	}
+
+	now := time.Now()
+	result = &types.DomainResult{
+		ID:          uuid.New().String(),
+		Domain:      domain,
+		Records:     make([]pkg.DNSRecord, 0),
+		Subdomains:  make([]types.Subdomain, 0),
+		Findings:    make([]types.DomainFinding, 0),
+		Owner:       idUser,
+		OwnerGroup:  groupID,
+		CreatedDate: now,
+		UpdatedDate: now,
+	}
+	return result, dbHandler.InsertDomainResult(result)
+}
+
+// saveDomainResult : stores the records of a domain and of its subdomains, merged with the ones of previous scans,
+// the findings of the domain itself and of the subdomains checked again are replaced. Nil records mean that they were
+// not looked up, the ones of previous scans are kept
+func saveDomainResult(
+	dbHandler *db.Handler,
+	idUser, groupID, domain string,
+	records []pkg.DNSRecord,
+	subdomains []types.Subdomain,
+	findings []types.DomainFinding,
+	checked []string,
+) error {
+	now := time.Now()
+	result, err := getDomainResult(dbHandler, idUser, groupID, domain)
+	if err != nil {
+		return err
+	}
+
+	scanned := map[string]bool{domain: true}
+	for _, name := range checked {
+		scanned[name] = true
+	}
+	kept := make([]types.DomainFinding, 0)
+	for _, f := range result.Findings {
+		if !scanned[f.Subdomain] {
+			kept = append(kept, f)
+		}
+	}
+	result.Findings = append(kept, findings...)
+
+	if records != nil {
+		result.Records = records
+	}
+	result.UpdatedDate = now
+	mergeSubdomains(result, subdomains, now)
+	return dbHandler.UpdateDomainResult(result)
+}
+
+// mergeSubdomains : adds subdomains seen at now to a domain result, the records and the IPs of the ones already there
+// are replaced unless they were not looked up
+func mergeSubdomains(result *types.DomainResult, subdomains []types.Subdomain, now time.Time) {
+	for _, sub := range subdomains {
+		exists := false
+		for idx := range result.Subdomains {
+			known := &result.Subdomains[idx]
+			if known.Name == sub.Name {
+				if sub.Records != nil {
+					known.Records = sub.Records
+					known.IPs = sub.IPs
+				}
+				known.LastSeen = now
+				// Subdomains found before the dates were kept have none
+				if known.FirstSeen.IsZero() {
+					known.FirstSeen = now
+				}
+				if known.Source == "" {
+					known.Source = sub.Source
+				}
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			if sub.Records == nil {
+				sub.Records = make([]pkg.DNSRecord, 0)
+				sub.IPs = make([]string, 0)
+			}
+			sub.FirstSeen = now
+			sub.LastSeen = now
+			result.Subdomains = append(result.Subdomains, sub)
+		}
+	}
 }
 
 func launchBuster(
+	ctx context.Context,
+	rec *events.Recorder,
+	port int,
 	url string,
+	base string,
 	dirs []string,
 	sCodes string,
 	wildCardForced bool,
 	excludedText string,
-) ([]pkg.GoBusterResult, error) {
+	params types.BusterParams,
+) ([]pkg.GoBusterResult, *pkg.WildcardBaseline, error) {
 	// Scan dirs
 	headers := []string{}
 	statusCodes := "200,204,301,302,307,401,403"
@@ -64,24 +397,41 @@ func launchBuster(
 	opts.Username = ""
 	opts.Password = ""
 	opts.UserAgent = "Mozilla/5.0 (Windows NT 6.1; Win64; x64; rv:47.0) Gecko/20100101 Firefox/47.0"
-
-	ctx := context.Background()
+	opts.Threads = params.Threads
+	opts.Rate = params.Rate
+	opts.Extensions = params.Extensions
+	opts.Depth = params.Depth
+	opts.Backups = params.Backups
+	opts.Base = base
 
 	buster, err := pkg.NewGobusterDir(ctx, opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	defer buster.Close()
 
 	err = buster.PreRun()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return buster.Run(dirs), nil
+	buster.OnHit(func(res pkg.GoBusterResult) {
+		rec.Emit(types.ScanEvent{
+			Kind:       events.KindBusterHit,
+			Stage:      events.StageBuster,
+			Port:       port,
+			Path:       res.Path,
+			StatusCode: res.StatusCode,
+		})
+	})
+
+	res := buster.Run(ctx, dirs)
+	return res, buster.Baseline(), nil
 }
 
 func getWebResult(
-	idUser string,
+	ctx context.Context,
+	rec *events.Recorder,
 	host string,
 	port int,
 	ssl bool,
@@ -90,39 +440,13 @@ func getWebResult(
 	statusCodes string,
 	wildcardForced bool,
 	excludedText string,
-	historyID string,
+	busterParams types.BusterParams,
 	runners []types.Runner,
 ) (types.WebResult, error) {
 	var err error
 	var url string
-	var historyRecord types.HistoryRecord
-
-	dbHandler := db.NewDBHandler()
-	defer dbHandler.CloseConnection()
 
 	webresult := types.WebResult{}
-
-	if historyID[0] == '-' {
-		historyRecord = types.HistoryRecord{
-			ID:          uuid.New().String(),
-			Owner:       idUser,
-			IsWeb:       true,
-			IsFinished:  false,
-			Host:        host,
-			CreatedDate: time.Now(),
-		}
-		historyRecord.State = append(historyRecord.State, "[*] Scan started")
-		historyRecord.State = append(historyRecord.State, "[*] Port : "+fmt.Sprintf("%d", port))
-		historyRecord.State = append(historyRecord.State, "[*] Base : "+base)
-		historyRecord.State = append(historyRecord.State, "[*] Wordlist : "+historyID[1:])
-		err = dbHandler.InsertHistoryRecord(historyRecord)
-	} else {
-		historyRecord, err = dbHandler.GetHistoryRecordByID(historyID)
-	}
-	if err != nil {
-		return webresult, err
-	}
-
 	webresult.Port = port
 	webresult.Ssl = ssl
 
@@ -131,71 +455,87 @@ func getWebResult(
 		proto = "https://"
 	}
 
-	url = fmt.Sprintf("%s%s:%d", proto, host, port)
+	url = proto + net.JoinHostPort(host, strconv.Itoa(port))
 
 	// Headergrab
+	rec.Started(events.StageHeaders, port, "")
 	p := pkg.NewHeaderGrabber()
-	webresult.Headers, err = p.Run(url)
+	webresult.Headers, err = p.Run(ctx, url)
 	if err != nil {
 		webresult.Err = append(webresult.Err, fmt.Sprintf("%s", err))
-		historyRecord.State = append(
-			historyRecord.State,
-			fmt.Sprintf("[-] Headers grabbing failed for port %d", port),
-		)
+		rec.Failed(events.StageHeaders, port, "", err)
 	} else {
-		historyRecord.State = append(
-			historyRecord.State,
-			fmt.Sprintf("[+] Headers grabbed for port %d", port),
-		)
+		rec.Finished(events.StageHeaders, port, "", len(webresult.Headers))
+
+		// Security headers audit
+		rec.Started(events.StageAudit, port, "")
+		a := pkg.NewHeaderAuditor()
+		webresult.Audit, err = a.Run(ctx, url, webresult.Headers)
+		if err != nil {
+			webresult.Err = append(webresult.Err, fmt.Sprintf("%s", err))
+			rec.Failed(events.StageAudit, port, "", err)
+		} else {
+			rec.Finished(events.StageAudit, port, "", len(webresult.Audit.Findings))
+		}
+	}
+
+	// TLS certificate and protocols
+	if ssl {
+		rec.Started(events.StageTLS, port, "")
+		g := pkg.NewTLSGrabber(5 * time.Second)
+		webresult.TLS, err = g.Run(ctx, host, port)
+		if err != nil {
+			webresult.Err = append(webresult.Err, fmt.Sprintf("%s", err))
+			rec.Failed(events.StageTLS, port, "", err)
+		} else {
+			rec.Finished(events.StageTLS, port, "", len(webresult.TLS.Issues))
+		}
+	}
+
+	// Technologies
+	rec.Started(events.StageTech, port, "")
+	t := pkg.NewTechDetector()
+	webresult.Technologies, err = t.Run(ctx, url)
+	if err != nil {
+		webresult.Err = append(webresult.Err, fmt.Sprintf("%s", err))
+		rec.Failed(events.StageTech, port, "", err)
+	} else {
+		rec.Finished(events.StageTech, port, "", len(webresult.Technologies))
 	}
-	dbHandler.UpdateHistoryRecord(historyRecord)
 
 	// Screen homepage
-	screener := pkg.NewScreener()
-	webresult.Screen, err = screener.Run(url)
+	rec.Started(events.StageScreenshot, port, "")
+	screener := pkg.NewScreener(pkg.DefaultBrowserPool(), screenerOptions())
+	webresult.Screen, err = screener.Run(ctx, url)
 	if err != nil {
 		webresult.Err = append(webresult.Err, fmt.Sprintf("%s", err))
-		historyRecord.State = append(
-			historyRecord.State,
-			fmt.Sprintf("[-] Screenshot failed for port %d", port),
-		)
+		rec.Failed(events.StageScreenshot, port, "", err)
+	} else if webresult.Screen.ID, err = screenshot.Save(webresult.Screen.Image); err != nil {
+		webresult.Err = append(webresult.Err, fmt.Sprintf("%s", err))
+		rec.Failed(events.StageScreenshot, port, "", err)
 	} else {
-		historyRecord.State = append(
-			historyRecord.State,
-			fmt.Sprintf("[+] Screenshot for port %d", port),
-		)
+		rec.Finished(events.StageScreenshot, port, "", 1)
 	}
-	dbHandler.UpdateHistoryRecord(historyRecord)
 
 	// GoBuster
-	if base != "" {
-		for idx := range dirs {
-			dirs[idx] = base + "/" + dirs[idx]
-		}
-	}
-	webresult.Busterres, err = launchBuster(url, dirs, statusCodes, wildcardForced, excludedText)
+	rec.Started(events.StageBuster, port, "")
+	webresult.Busterres, webresult.Baseline, err = launchBuster(ctx, rec, port, url, base, dirs, statusCodes, wildcardForced, excludedText, busterParams)
 	if err != nil {
 		webresult.Err = append(webresult.Err, fmt.Sprintf("%s", err))
-		historyRecord.State = append(
-			historyRecord.State,
-			fmt.Sprintf("[-] GoBuster failed for port %d / Error : %s", port, err),
-		)
+		rec.Failed(events.StageBuster, port, "", err)
 	} else {
-		historyRecord.State = append(
-			historyRecord.State,
-			fmt.Sprintf("[+] GoBuster finished for port %d / Found : %d", port, len(webresult.Busterres)),
-		)
+		rec.Finished(events.StageBuster, port, "", len(webresult.Busterres))
+		if webresult.Baseline != nil {
+			rec.Info(fmt.Sprintf("Wildcard responses detected for port %d, %d hits dropped", port, webresult.Baseline.Dropped))
+		}
 	}
-	dbHandler.UpdateHistoryRecord(historyRecord)
 
 	for idx := range runners {
+		if ctx.Err() != nil {
+			break
+		}
 
-		historyRecord.State = append(
-			historyRecord.State,
-			fmt.Sprintf("[*] %s started for port %d", runners[idx].DisplayName, port),
-		)
-		dbHandler.UpdateHistoryRecord(historyRecord)
-		r, err := launchRunner(host, port, proto, runners[idx])
+		r, err := launchRunner(ctx, rec, host, port, proto, runners[idx])
 		if err == nil {
 			exists := false
 			for idx := range webresult.RunnerOutput {
@@ -207,29 +547,57 @@ func getWebResult(
 			if !exists {
 				webresult.RunnerOutput = append(webresult.RunnerOutput, r)
 			}
-			historyRecord.State = append(
-				historyRecord.State,
-				fmt.Sprintf("[+] %s Finished for port %d", runners[idx].DisplayName, port),
-			)
 		} else {
 			webresult.Err = append(webresult.Err, fmt.Sprintf("%s", err))
-			historyRecord.State = append(
-				historyRecord.State,
-				fmt.Sprintf("[-] %s Failed for port %d", runners[idx].DisplayName, port),
-			)
 		}
-		dbHandler.UpdateHistoryRecord(historyRecord)
 	}
 
-	if historyRecord.IsWeb {
-		historyRecord.IsFinished = true
-		historyRecord.IsSuccess = true
-		dbHandler.UpdateHistoryRecord(historyRecord)
-	}
 	return webresult, nil
 }
 
-func fingerprintPort(host string, port int) (bool, bool) {
+// screenerOptions : returns the options of the screenshots from the configuration
+func screenerOptions() pkg.ScreenerOptions {
+	cfg := config.Cfg.Screenshots
+	return pkg.ScreenerOptions{
+		Timeout:  time.Duration(cfg.Timeout) * time.Second,
+		IdleWait: time.Duration(cfg.IdleWait) * time.Second,
+		Width:    cfg.Width,
+		Height:   cfg.Height,
+		FullPage: cfg.FullPage,
+	}
+}
+
+// SecuritySummary : aggregates the headers audits of web results
+func SecuritySummary(webResults []types.WebResult) *pkg.SecuritySummary {
+	audits := make([]*pkg.HeaderAudit, 0, len(webResults))
+	for _, wr := range webResults {
+		audits = append(audits, wr.Audit)
+	}
+	return pkg.Summarize(audits)
+}
+
+// techTags : returns the tags of the technologies of a web result
+func techTags(webresult types.WebResult) []string {
+	tags := make([]string, 0)
+	if !config.Cfg.Technologies.AutoTag {
+		return tags
+	}
+	for _, t := range webresult.Technologies {
+		tag := "#" + strings.Trim(tagRegexp.ReplaceAllString(strings.ToLower(t.Name), "-"), "-")
+		if tag != "#" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func fingerprintPort(ctx context.Context, host string, service pkg.Service) (bool, bool) {
+	// Trust the service detection when it identified the port
+	if service.Name != "" && service.Name != "tls" {
+		return service.Name == "http", service.TLS
+	}
+
+	port := service.Port
 	if port == 80 {
 		return true, false
 	}
@@ -237,22 +605,30 @@ func fingerprintPort(host string, port int) (bool, bool) {
 		return true, true
 	}
 
-	url := fmt.Sprintf("http://%s:%d", host, port)
-	_, err := http.Get(url)
-	if err == nil {
+	get := func(url string) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := pkg.InsecureClient.Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	if get("http://"+net.JoinHostPort(host, strconv.Itoa(port))) == nil {
 		return true, false
 	}
 
-	url = fmt.Sprintf("https://%s:%d", host, port)
-	_, err = http.Get(url)
-	if err == nil {
+	if get("https://"+net.JoinHostPort(host, strconv.Itoa(port))) == nil {
 		return true, true
 	}
 
 	return false, false
 }
 
-func launchRunner(host string, port int, proto string, r types.Runner) (types.RunnerResult, error) {
+func launchRunner(ctx context.Context, rec *events.Recorder, host string, port int, proto string, r types.Runner) (types.RunnerResult, error) {
 	p := fmt.Sprintf("%d", port)
 	for idx := range r.Cmd {
 		r.Cmd[idx] = strings.ReplaceAll(r.Cmd[idx], "[[host]]", host)
@@ -260,15 +636,28 @@ func launchRunner(host string, port int, proto string, r types.Runner) (types.Ru
 		r.Cmd[idx] = strings.ReplaceAll(r.Cmd[idx], "[[proto]]", proto)
 	}
 
+	rec.Started(events.StageRunner, port, r.DisplayName)
 	runnerHandler := runner.NewRunnerHandler()
-	_, err := runnerHandler.PullImage(r.Tag)
+	_, err := runnerHandler.PullImage(ctx, r.Tag)
 	if err != nil {
+		rec.Failed(events.StageRunner, port, r.DisplayName, err)
 		return types.RunnerResult{}, err
 	}
-	stdout, stderr, err := runnerHandler.RunCmd(r.Tag, r.Cmd)
+	stdout, stderr, err := runnerHandler.RunCmd(ctx, r.Tag, r.Cmd, func(stream string, chunk []byte) {
+		rec.Emit(types.ScanEvent{
+			Kind:    events.KindRunnerOutput,
+			Stage:   events.StageRunner,
+			Port:    port,
+			Tool:    r.DisplayName,
+			Stream:  stream,
+			Message: string(chunk),
+		})
+	})
 	if err != nil {
+		rec.Failed(events.StageRunner, port, r.DisplayName, err)
 		return types.RunnerResult{}, err
 	}
+	rec.Finished(events.StageRunner, port, r.DisplayName, 1)
 
 	res := types.RunnerResult{}
 	res.ID = uuid.New().String()
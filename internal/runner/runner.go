@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"strings"
 
 	faryukTypes "FaRyuk/internal/types"
@@ -15,13 +16,28 @@ import (
 	"github.com/google/uuid"
 )
 
+// OutputHandler : receives chunks of a container output as they are written
+type OutputHandler func(stream string, chunk []byte)
+
+// chunkWriter : buffers a container stream and forwards each chunk to an OutputHandler
+type chunkWriter struct {
+	buf     *bytes.Buffer
+	stream  string
+	handler OutputHandler
+}
+
+func (c chunkWriter) Write(p []byte) (int, error) {
+	if c.handler != nil {
+		c.handler(c.stream, p)
+	}
+	return c.buf.Write(p)
+}
+
 type RunnerHandler struct {
-	ctx context.Context
 	cli *client.Client
 }
 
 func NewRunnerHandler() *RunnerHandler {
-	ctx := context.Background()
 	// cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 
 	cli, err := client.NewEnvClient()
@@ -29,7 +45,7 @@ func NewRunnerHandler() *RunnerHandler {
 		panic(err)
 	}
 
-	return &RunnerHandler{ctx, cli}
+	return &RunnerHandler{cli}
 }
 
 func NewRunner(tag string, displayName string, cmd []string, owner string, isWeb bool, isPort bool) *faryukTypes.Runner {
@@ -46,23 +62,31 @@ func NewRunner(tag string, displayName string, cmd []string, owner string, isWeb
 	}
 }
 
-func (rHandler *RunnerHandler) PullImage(tag string) (string, error) {
+func (rHandler *RunnerHandler) PullImage(ctx context.Context, tag string) (string, error) {
 	fullname := tag
 	if ss := strings.Split(tag, "/"); len(ss) == 1 {
 		fullname = fmt.Sprintf("docker.io/library/%s", tag)
 	} else if len(ss) == 2 {
 		fullname = fmt.Sprintf("docker.io/%s", tag)
 	}
-	_, err := rHandler.cli.ImagePull(rHandler.ctx, fullname, types.ImagePullOptions{})
+	out, err := rHandler.cli.ImagePull(ctx, fullname, types.ImagePullOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	// The pull is only complete once its progress stream is drained
+	_, err = io.Copy(io.Discard, out)
 	if err != nil {
 		return "", err
 	}
 	return tag, nil
 }
 
-func (rHandler *RunnerHandler) RunCmd(imgId string, cmd []string) (string, string, error) {
+// RunCmd : runs cmd in a new container of image imgId, onOutput may be nil
+func (rHandler *RunnerHandler) RunCmd(ctx context.Context, imgId string, cmd []string, onOutput OutputHandler) (string, string, error) {
 	var stdout, stderr bytes.Buffer
-	resp, err := rHandler.cli.ContainerCreate(rHandler.ctx, &container.Config{
+	resp, err := rHandler.cli.ContainerCreate(ctx, &container.Config{
 		Image: imgId,
 		Cmd:   cmd,
 		Tty:   false,
@@ -72,36 +96,48 @@ func (rHandler *RunnerHandler) RunCmd(imgId string, cmd []string) (string, strin
 	}
 
 	defer func() {
-		err := rHandler.cli.ContainerRemove(rHandler.ctx, resp.ID, types.ContainerRemoveOptions{})
+		// Force removal so a cancelled run does not leave its container behind
+		err := rHandler.cli.ContainerRemove(context.Background(), resp.ID, types.ContainerRemoveOptions{Force: true})
 		if err != nil {
 			return
 		}
 	}()
 
-	if err = rHandler.cli.ContainerStart(rHandler.ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+	if err = rHandler.cli.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
 		return "", "", err
 	}
 
-	statusCh, errCh := rHandler.cli.ContainerWait(rHandler.ctx, resp.ID, container.WaitConditionNotRunning)
-	select {
-	case err = <-errCh:
-		if err != nil {
-			return "", "", err
+	kill := func() {
+		if ctx.Err() != nil {
+			// ctx is done, it can't be used to stop the container
+			rHandler.cli.ContainerKill(context.Background(), resp.ID, "SIGKILL")
 		}
-	case <-statusCh:
 	}
 
-	out, err := rHandler.cli.ContainerLogs(rHandler.ctx, resp.ID,
+	// Follow logs while the container runs so output can be streamed
+	out, err := rHandler.cli.ContainerLogs(ctx, resp.ID,
 		types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Follow: true})
 	if err != nil {
+		kill()
 		return "", "", err
 	}
 	defer out.Close()
 
-	_, err = stdcopy.StdCopy(&stdout, &stderr, out)
+	_, err = stdcopy.StdCopy(chunkWriter{&stdout, "stdout", onOutput}, chunkWriter{&stderr, "stderr", onOutput}, out)
 	if err != nil {
+		kill()
 		return "", "", err
 	}
 
+	statusCh, errCh := rHandler.cli.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	select {
+	case err = <-errCh:
+		if err != nil {
+			kill()
+			return "", "", err
+		}
+	case <-statusCh:
+	}
+
 	return stdout.String(), stderr.String(), nil
 }
@@ -0,0 +1,109 @@
+package screenshot
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"FaRyuk/internal/blob"
+	"FaRyuk/internal/db"
+	"FaRyuk/pkg"
+
+	"github.com/google/uuid"
+)
+
+// bucket : bucket of the blob store holding the screenshots
+const bucket = "screenshots"
+
+// thumbnailWidth : width in pixels of the thumbnails
+const thumbnailWidth = 320
+
+func thumbnailID(id string) string {
+	return id + "-thumbnail"
+}
+
+// Save : stores a screenshot and its thumbnail, it returns the ID of the screenshot
+func Save(image []byte) (string, error) {
+	store, err := blob.NewStore(bucket)
+	if err != nil {
+		return "", err
+	}
+	thumbnail, err := pkg.Thumbnail(image, thumbnailWidth)
+	if err != nil {
+		return "", err
+	}
+
+	id := uuid.New().String()
+	err = store.Put(id, image)
+	if err != nil {
+		return "", err
+	}
+	err = store.Put(thumbnailID(id), thumbnail)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Get : returns a screenshot or its thumbnail
+func Get(id string, thumbnail bool) ([]byte, error) {
+	store, err := blob.NewStore(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if thumbnail {
+		id = thumbnailID(id)
+	}
+	return store.Get(id)
+}
+
+// Remove : removes a screenshot and its thumbnail, removing a missing screenshot is not an error
+func Remove(id string) error {
+	if id == "" {
+		return nil
+	}
+	store, err := blob.NewStore(bucket)
+	if err != nil {
+		return err
+	}
+	for _, blobID := range []string{id, thumbnailID(id)} {
+		err = store.Remove(blobID)
+		if err != nil && !errors.Is(err, blob.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migrate : moves the base64 screenshots still stored in results to the blob store, it returns the number moved
+func Migrate(dbHandler *db.Handler) (int, error) {
+	migrated := 0
+	for {
+		result := dbHandler.GetResultWithLegacyScreenshot()
+		if result == nil {
+			return migrated, nil
+		}
+
+		for idx := range result.WebResults {
+			screen := &result.WebResults[idx].Screen
+			if screen.Path == "" {
+				continue
+			}
+			// Screenshots which cannot be decoded are dropped
+			image, err := base64.StdEncoding.DecodeString(screen.Path)
+			if err == nil && len(image) != 0 {
+				screen.ID, err = Save(image)
+				if err != nil {
+					return migrated, err
+				}
+				screen.Hash, _ = pkg.PerceptualHash(image)
+				migrated++
+			}
+			screen.Path = ""
+		}
+
+		if !dbHandler.UpdateResult(result) {
+			return migrated, fmt.Errorf("could not update result %s", result.ID)
+		}
+	}
+}
@@ -10,5 +10,11 @@ import (
 func NewSharing(owner, result, user string) *types.Sharing {
 	id := uuid.New().String()
 
-	return &types.Sharing{id, user, owner, result, "Pending"}
+	return &types.Sharing{
+		ID:       id,
+		UserID:   user,
+		OwnerID:  owner,
+		ResultID: result,
+		State:    "Pending",
+	}
 }
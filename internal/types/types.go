@@ -8,30 +8,38 @@ import (
 
 // WebResult : struct for webresults of a port
 type WebResult struct {
-	Port         int                  `bson:"port" json:"port"`
-	Ssl          bool                 `bson:"ssl" json:"ssl"`
-	Headers      map[string][]string  `bson:"headers" json:"headers"`
-	Busterres    []pkg.GoBusterResult `bson:"busterres" json:"busterres"`
-	Screen       pkg.ScreenerResult   `bson:"screener" json:"screen"`
-	RunnerOutput []RunnerResult       `bson:"runnerOutput" json:"runnerOutput"`
-	CreatedDate  time.Time            `bson:"createdDate" json:"createdDate"`
-	Err          []string             `bson:"err" json:"err"`
+	Port         int                   `bson:"port" json:"port"`
+	Ssl          bool                  `bson:"ssl" json:"ssl"`
+	Headers      map[string][]string   `bson:"headers" json:"headers"`
+	Audit        *pkg.HeaderAudit      `bson:"audit" json:"audit"`
+	TLS          *pkg.TLSInfo          `bson:"tls" json:"tls"`
+	Technologies []pkg.Technology      `bson:"technologies" json:"technologies"`
+	Busterres    []pkg.GoBusterResult  `bson:"busterres" json:"busterres"`
+	Baseline     *pkg.WildcardBaseline `bson:"baseline" json:"baseline"`
+	Screen       pkg.ScreenerResult    `bson:"screener" json:"screen"`
+	RunnerOutput []RunnerResult        `bson:"runnerOutput" json:"runnerOutput"`
+	CreatedDate  time.Time             `bson:"createdDate" json:"createdDate"`
+	Err          []string              `bson:"err" json:"err"`
 }
 
 // Result : struct for result of a host
 type Result struct {
-	ID           string         `bson:"id" json:"id"`
-	Host         string         `bson:"host" json:"host"`
-	Ips          []string       `bson:"ips" json:"ips"`
-	OpenPorts    []int          `bson:"openPorts" json:"openPorts"`
-	WebResults   []WebResult    `bson:"webResults" json:"webResults"`
-	Tags         []string       `bson:"tags" json:"tags"`
-	RunnerOutput []RunnerResult `bson:"runnerOutput" json:"runnerOutput"`
-	Owner        string         `bson:"owner" json:"owner"`
-	SharedWith   []string       `bson:"sharedWith" json:"sharedWith"`
-	OwnerGroup   string         `bson:"ownerGroup" json:"ownerGroup"`
-	CreatedDate  time.Time      `bson:"createdDate" json:"createdDate"`
-	Err          []string       `bson:"err" json:"err"`
+	ID           string               `bson:"id" json:"id"`
+	Host         string               `bson:"host" json:"host"`
+	Ips          []string             `bson:"ips" json:"ips"`
+	OpenPorts    []int                `bson:"openPorts" json:"openPorts"`
+	OpenUDPPorts []pkg.UDPPort        `bson:"openUdpPorts" json:"openUdpPorts"`
+	Services     []pkg.Service        `bson:"services" json:"services"`
+	Security     *pkg.SecuritySummary `bson:"security" json:"security"`
+	Candidates   []string             `bson:"candidates" json:"candidates"`
+	WebResults   []WebResult          `bson:"webResults" json:"webResults"`
+	Tags         []string             `bson:"tags" json:"tags"`
+	RunnerOutput []RunnerResult       `bson:"runnerOutput" json:"runnerOutput"`
+	Owner        string               `bson:"owner" json:"owner"`
+	SharedWith   []string             `bson:"sharedWith" json:"sharedWith"`
+	OwnerGroup   string               `bson:"ownerGroup" json:"ownerGroup"`
+	CreatedDate  time.Time            `bson:"createdDate" json:"createdDate"`
+	Err          []string             `bson:"err" json:"err"`
 }
 
 // Runner
@@ -54,6 +62,105 @@ type RunnerResult struct {
 	Stderr      string `bson:"stderr" json:"stderr"`
 }
 
+// ScreenshotMember : web result of a group of similar screenshots
+type ScreenshotMember struct {
+	ResultID     string `bson:"resultId" json:"resultId"`
+	Host         string `bson:"host" json:"host"`
+	Port         int    `bson:"port" json:"port"`
+	ScreenshotID string `bson:"screenshotId" json:"screenshotId"`
+	Hash         string `bson:"hash" json:"hash"`
+}
+
+// ScreenshotCluster : web results showing visually similar pages
+type ScreenshotCluster struct {
+	Hash    string             `bson:"hash" json:"hash"`
+	Size    int                `bson:"size" json:"size"`
+	Members []ScreenshotMember `bson:"members" json:"members"`
+}
+
+// How subdomains were discovered
+const (
+	SourceBruteForce   = "bruteforce"
+	SourceZoneTransfer = "axfr"
+	SourcePermutation  = "permutation"
+	SourceImport       = "import"
+)
+
+// Subdomain : subdomain found by a domain scan and its DNS records
+type Subdomain struct {
+	Name    string          `bson:"name" json:"name"`
+	Records []pkg.DNSRecord `bson:"records" json:"records"`
+	IPs     []string        `bson:"ips" json:"ips"`
+	// Source is how the subdomain was first discovered
+	Source    string    `bson:"source" json:"source"`
+	FirstSeen time.Time `bson:"firstSeen" json:"firstSeen"`
+	LastSeen  time.Time `bson:"lastSeen" json:"lastSeen"`
+	// ResultID is the result of the scan of the subdomain, it is only set when a domain result is read alone
+	ResultID string `bson:"-" json:"resultId"`
+}
+
+// Kinds of domain findings
+const (
+	FindingTakeover = "takeover"
+	FindingAXFR     = "axfr"
+)
+
+// DomainFinding : issue found by a domain scan
+type DomainFinding struct {
+	Kind        string           `bson:"kind" json:"kind"`
+	Severity    string           `bson:"severity" json:"severity"`
+	Subdomain   string           `bson:"subdomain" json:"subdomain"`
+	Message     string           `bson:"message" json:"message"`
+	Takeover    *pkg.Takeover    `bson:"takeover" json:"takeover"`
+	Transfer    *TransferFinding `bson:"transfer" json:"transfer"`
+	CreatedDate time.Time        `bson:"createdDate" json:"createdDate"`
+}
+
+// TransferFinding : nameserver allowing zone transfers, the records of the zone are not kept, only their number and
+// the names they define
+type TransferFinding struct {
+	Nameserver string   `bson:"nameserver" json:"nameserver"`
+	Address    string   `bson:"address" json:"address"`
+	Records    int      `bson:"records" json:"records"`
+	Names      []string `bson:"names" json:"names"`
+}
+
+// ImportSummary : outcome of the import of a subdomain dataset
+type ImportSummary struct {
+	Domain string `json:"domain"`
+	// Total is the number of distinct subdomains of the domain in the dataset
+	Total      int      `json:"total"`
+	Duplicates int      `json:"duplicates"`
+	Unresolved int      `json:"unresolved"`
+	Imported   []string `json:"imported"`
+	Scanned    int      `json:"scanned"`
+}
+
+// BruteForceState : progress of the subdomain brute force of a domain, kept to resume an interrupted scan
+type BruteForceState struct {
+	Wordlist string `bson:"wordlist" json:"wordlist"`
+	// Offset is the number of words of the wordlist already processed
+	Offset      int       `bson:"offset" json:"offset"`
+	Total       int       `bson:"total" json:"total"`
+	Found       []string  `bson:"found" json:"found"`
+	Finished    bool      `bson:"finished" json:"finished"`
+	UpdatedDate time.Time `bson:"updatedDate" json:"updatedDate"`
+}
+
+// DomainResult : results of the domain scans of a domain
+type DomainResult struct {
+	ID          string           `bson:"id" json:"id"`
+	Domain      string           `bson:"domain" json:"domain"`
+	Records     []pkg.DNSRecord  `bson:"records" json:"records"`
+	Subdomains  []Subdomain      `bson:"subdomains" json:"subdomains"`
+	Findings    []DomainFinding  `bson:"findings" json:"findings"`
+	BruteForce  *BruteForceState `bson:"bruteForce" json:"bruteForce"`
+	Owner       string           `bson:"owner" json:"owner"`
+	OwnerGroup  string           `bson:"ownerGroup" json:"ownerGroup"`
+	CreatedDate time.Time        `bson:"createdDate" json:"createdDate"`
+	UpdatedDate time.Time        `bson:"updatedDate" json:"updatedDate"`
+}
+
 // HistoryRecord : record of the history of a scan
 type HistoryRecord struct {
 	ID          string    `bson:"id" json:"id"`
@@ -66,6 +173,91 @@ type HistoryRecord struct {
 	Owner       string    `bson:"owner" json:"owner"`
 	OwnerGroup  string    `bson:"ownerGroup" json:"ownerGroup"`
 	CreatedDate time.Time `bson:"createdDate" json:"createdDate"`
+	// Stages is computed from the history events, it is not stored with the record
+	Stages []StageDuration `bson:"-" json:"stages"`
+}
+
+// ScanEvent : typed event of a scan, stored in the history events and published while the scan runs. StatusCode is
+// the HTTP status of a buster hit and Stream the output, stdout or stderr, of a runner
+type ScanEvent struct {
+	ID          string    `bson:"id" json:"id"`
+	HistoryID   string    `bson:"historyId" json:"historyId"`
+	Kind        string    `bson:"kind" json:"kind"`
+	Stage       string    `bson:"stage" json:"stage"`
+	Severity    string    `bson:"severity" json:"severity"`
+	Port        int       `bson:"port" json:"port"`
+	Ports       []int     `bson:"ports" json:"ports"`
+	Tool        string    `bson:"tool" json:"tool"`
+	Count       int       `bson:"count" json:"count"`
+	Path        string    `bson:"path" json:"path"`
+	StatusCode  int       `bson:"statusCode" json:"statusCode"`
+	Stream      string    `bson:"stream" json:"stream"`
+	Message     string    `bson:"message" json:"message"`
+	Err         string    `bson:"err" json:"err"`
+	CreatedDate time.Time `bson:"createdDate" json:"createdDate"`
+}
+
+// StageDuration : time spent in a stage of a scan, computed from its events
+type StageDuration struct {
+	Stage        string    `bson:"stage" json:"stage"`
+	Port         int       `bson:"port" json:"port"`
+	Tool         string    `bson:"tool" json:"tool"`
+	Severity     string    `bson:"severity" json:"severity"`
+	StartedDate  time.Time `bson:"startedDate" json:"startedDate"`
+	FinishedDate time.Time `bson:"finishedDate" json:"finishedDate"`
+	Duration     float64   `bson:"duration" json:"duration"`
+}
+
+// JobParams : parameters needed to (re)launch a scan job
+type JobParams struct {
+	Host           string   `bson:"host" json:"host"`
+	Domain         string   `bson:"domain" json:"domain"`
+	ResultID       string   `bson:"resultId" json:"resultId"`
+	Portlist       string   `bson:"portlist" json:"portlist"`
+	Dirlist        string   `bson:"dirlist" json:"dirlist"`
+	Dnslist        string   `bson:"dnslist" json:"dnslist"`
+	Resolver       string   `bson:"resolver" json:"resolver"`
+	Resolvers      []string `bson:"resolvers" json:"resolvers"`
+	Port           int      `bson:"port" json:"port"`
+	Ssl            bool     `bson:"ssl" json:"ssl"`
+	Base           string   `bson:"base" json:"base"`
+	StatusCodes    string   `bson:"statusCodes" json:"statusCodes"`
+	WildcardForced bool     `bson:"wildcardForced" json:"wildcardForced"`
+	ExcludedText   string   `bson:"excludedText" json:"excludedText"`
+	Rescan         bool     `bson:"rescan" json:"rescan"`
+	Scanners       []string `bson:"scanners" json:"scanners"`
+	Timeout        int      `bson:"timeout" json:"timeout"`
+	FollowSANs     bool     `bson:"followSans" json:"followSans"`
+	Discover       bool     `bson:"discover" json:"discover"`
+	Tags           []string `bson:"tags" json:"tags"`
+	Resume         bool     `bson:"resume" json:"resume"`
+	Permutations   int      `bson:"permutations" json:"permutations"`
+	Dataset        string   `bson:"dataset" json:"dataset"`
+	BusterParams   `bson:",inline"`
+}
+
+// BusterParams : tuning of the directory busting of a webscan or of the subdomain brute force of a domain scan
+type BusterParams struct {
+	Threads    int      `bson:"threads" json:"threads"`
+	Rate       int      `bson:"rate" json:"rate"`
+	Extensions []string `bson:"extensions" json:"extensions"`
+	Depth      int      `bson:"depth" json:"depth"`
+	Backups    bool     `bson:"backups" json:"backups"`
+}
+
+// Job : scan job handled by the job queue
+type Job struct {
+	ID           string    `bson:"id" json:"id"`
+	Kind         string    `bson:"kind" json:"kind"`
+	State        string    `bson:"state" json:"state"`
+	Params       JobParams `bson:"params" json:"params"`
+	Owner        string    `bson:"owner" json:"owner"`
+	OwnerGroup   string    `bson:"ownerGroup" json:"ownerGroup"`
+	ParentID     string    `bson:"parentId" json:"parentId"`
+	Err          string    `bson:"err" json:"err"`
+	CreatedDate  time.Time `bson:"createdDate" json:"createdDate"`
+	StartedDate  time.Time `bson:"startedDate" json:"startedDate"`
+	FinishedDate time.Time `bson:"finishedDate" json:"finishedDate"`
 }
 
 // Comment : struct for comment on a result
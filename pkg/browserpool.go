@@ -0,0 +1,161 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/chromedp/chromedp"
+)
+
+// BrowserPool : headless Chrome instances shared by screenshots, each screenshot uses its own tab
+type BrowserPool struct {
+	mu       sync.Mutex
+	browsers []*browser
+	// slots : index of the browser of each tab which may be opened
+	slots  chan int
+	closed bool
+}
+
+type browser struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+var (
+	defaultPoolMu sync.Mutex
+	defaultPool   *BrowserPool
+)
+
+// NewBrowserPool : returns a pool of size browsers with at most tabs tabs each, browsers are started on first use
+func NewBrowserPool(size, tabs int) *BrowserPool {
+	if size < 1 {
+		size = 1
+	}
+	if tabs < 1 {
+		tabs = 1
+	}
+	p := &BrowserPool{
+		browsers: make([]*browser, size),
+		slots:    make(chan int, size*tabs),
+	}
+	for t := 0; t < tabs; t++ {
+		for idx := 0; idx < size; idx++ {
+			p.slots <- idx
+		}
+	}
+	return p
+}
+
+// SetBrowserPool : replaces the pool used by screeners, the previous one is closed
+func SetBrowserPool(p *BrowserPool) {
+	defaultPoolMu.Lock()
+	defer defaultPoolMu.Unlock()
+	if defaultPool != nil {
+		defaultPool.Close()
+	}
+	defaultPool = p
+}
+
+// DefaultBrowserPool : returns the pool used by screeners, a single browser unless SetBrowserPool was called
+func DefaultBrowserPool() *BrowserPool {
+	defaultPoolMu.Lock()
+	defer defaultPoolMu.Unlock()
+	if defaultPool == nil {
+		defaultPool = NewBrowserPool(1, 1)
+	}
+	return defaultPool
+}
+
+// startBrowser : starts a headless Chrome ignoring ssl errors
+func startBrowser() (*browser, error) {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("ignore-certificate-errors", "1"),
+	)
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
+	ctx, cancel := chromedp.NewContext(allocCtx)
+
+	// Running no action starts the browser
+	err := chromedp.Run(ctx)
+	if err != nil {
+		cancel()
+		cancelAlloc()
+		return nil, err
+	}
+	return &browser{ctx, func() {
+		cancel()
+		cancelAlloc()
+	}}, nil
+}
+
+// browserContext : returns the context of a browser of the pool, it is (re)started if it is not running
+func (p *BrowserPool) browserContext(idx int) (context.Context, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, errors.New("browser pool is closed")
+	}
+
+	b := p.browsers[idx]
+	if b != nil && b.ctx.Err() == nil {
+		return b.ctx, nil
+	}
+	if b != nil {
+		b.cancel()
+	}
+	b, err := startBrowser()
+	p.browsers[idx] = b
+	if err != nil {
+		return nil, err
+	}
+	return b.ctx, nil
+}
+
+// NewTab : opens a tab once one is free, the tab is closed when ctx is done or when the returned function is called,
+// which must be done to free the tab
+func (p *BrowserPool) NewTab(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	var idx int
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case idx = <-p.slots:
+	}
+
+	browserCtx, err := p.browserContext(idx)
+	if err != nil {
+		p.slots <- idx
+		return nil, nil, err
+	}
+	tabCtx, cancelTab := chromedp.NewContext(browserCtx)
+
+	done := make(chan bool)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancelTab()
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return tabCtx, func() {
+		once.Do(func() {
+			close(done)
+			cancelTab()
+			p.slots <- idx
+		})
+	}, nil
+}
+
+// Close : kills the browsers of the pool
+func (p *BrowserPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for idx, b := range p.browsers {
+		if b != nil {
+			b.cancel()
+			p.browsers[idx] = nil
+		}
+	}
+}
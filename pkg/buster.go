@@ -1,11 +1,19 @@
 package pkg
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
-	"io"
+	"html"
 	"net/http"
+	neturl "net/url"
+	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/OJ/gobuster/v3/helper"
 	"github.com/OJ/gobuster/v3/libgobuster"
@@ -14,9 +22,36 @@ import (
 
 // GoBusterResult a structure that stores results from GoBuster
 type GoBusterResult struct {
-	Path       string `bson:"path" json:"path"`
-	StatusCode int    `bson:"statusCode" json:"statusCode"`
-	Size       int    `bson:"size" json:"size"`
+	Path        string `bson:"path" json:"path"`
+	StatusCode  int    `bson:"statusCode" json:"statusCode"`
+	Size        int    `bson:"size" json:"size"`
+	ParentPath  string `bson:"parentPath" json:"parentPath"`
+	Depth       int    `bson:"depth" json:"depth"`
+	Location    string `bson:"location" json:"location"`
+	ContentType string `bson:"contentType" json:"contentType"`
+	Title       string `bson:"title" json:"title"`
+	Words       int    `bson:"words" json:"words"`
+	Lines       int    `bson:"lines" json:"lines"`
+	// ResponseTime is in milliseconds
+	ResponseTime int64  `bson:"responseTime" json:"responseTime"`
+	Hash         string `bson:"hash" json:"hash"`
+
+	isDir bool
+}
+
+// maxTitleLength is the maximum length of the titles kept
+const maxTitleLength = 256
+
+var titleRegexp = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// backupSuffixes are appended to every file found when looking for backups
+var backupSuffixes = []string{"~", ".old", ".swp"}
+
+// candidate is a path to try along with its position in the tree
+type candidate struct {
+	path   string
+	parent string
+	depth  int
 }
 
 // OptionsDir is the struct to hold all options for this plugin
@@ -24,8 +59,21 @@ type OptionsDir struct {
 	libgobuster.HTTPOptions
 	StatusCodes       string
 	StatusCodesParsed libgobuster.IntSet
-	WildcardForced    bool
-	ExcludeText       string
+	// WildcardForced keeps the hits looking like the responses to non existing paths
+	WildcardForced bool
+	ExcludeText    string
+	// Threads is the number of concurrent requests
+	Threads int
+	// Rate is the maximum number of requests per second sent to the host, 0 means unlimited
+	Rate int
+	// Extensions are appended to every word, e.g. php or bak
+	Extensions []string
+	// Depth is how many levels of discovered directories are scanned, 0 means no recursion
+	Depth int
+	// Backups enables looking for backup copies of every file found
+	Backups bool
+	// Base is the path the words are looked up under, e.g. app for http://host/app/word
+	Base string
 }
 
 // GobusterDir is the main type to implement the interface
@@ -33,11 +81,29 @@ type GobusterDir struct {
 	options    *OptionsDir
 	globalopts *libgobuster.Options
 	http       *libgobuster.HTTPClient
+	ctx        context.Context
+	throttle   *Throttle
+	release    func()
+	baseline   *WildcardBaseline
+	dropped    int32
+	onHit      func(GoBusterResult)
 }
 
 // NewGoBusterResult returns a new GoBusterResult struct
 func NewGoBusterResult(path string, statusCode, size int) *GoBusterResult {
-	return &GoBusterResult{path, statusCode, size}
+	return &GoBusterResult{Path: path, StatusCode: statusCode, Size: size}
+}
+
+// ParseExtensions parses a comma separated list of extensions, leading dots are removed
+func ParseExtensions(extensions string) []string {
+	var ret []string
+	for _, ext := range strings.Split(extensions, ",") {
+		ext = strings.TrimPrefix(strings.TrimSpace(ext), ".")
+		if ext != "" {
+			ret = append(ret, ext)
+		}
+	}
+	return ret
 }
 
 // NewOptionsDir returns a new initialized OptionsDir
@@ -63,28 +129,26 @@ func NewOptionsDir(statusCodes string, headers []string, wildcard bool, excludeT
 		StatusCodesParsed: parsed,
 		WildcardForced:    wildcard,
 		ExcludeText:       excludeText,
+		Threads:           1,
 	}
 	ret.Headers = optheaders
 	return ret
 }
 
 func (d *GobusterDir) get(url string) (statusCode *int, size int64, header http.Header, body []byte, err error) {
-	var client http.Client
-	resp, err := client.Get(url)
-	if err != nil {
-		return nil, 0, nil, nil, err
-	}
-
-	body, err = io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, 0, nil, nil, err
-	}
+	statusCode, size, header, body, _, err = d.timedGet(url)
+	return statusCode, size, header, body, err
+}
 
-	statusCode, size, header, _, err = d.http.Request(url, libgobuster.RequestOptions{})
+// timedGet sends a request once the throttle allows it and measures how long the server took to answer
+func (d *GobusterDir) timedGet(url string) (statusCode *int, size int64, header http.Header, body []byte, elapsed time.Duration, err error) {
+	err = d.throttle.Wait(d.ctx)
 	if err != nil {
-		return nil, 0, nil, nil, err
+		return nil, 0, nil, nil, 0, err
 	}
-	return statusCode, size, header, body, err
+	start := time.Now()
+	statusCode, size, header, body, err = d.http.Request(url, libgobuster.RequestOptions{ReturnBody: true})
+	return statusCode, size, header, body, time.Since(start), err
 }
 
 // NewGobusterDir creates a new initialized Buster
@@ -95,7 +159,14 @@ func NewGobusterDir(cont context.Context, opts *OptionsDir) (*GobusterDir, error
 
 	g := GobusterDir{
 		options: opts,
+		ctx:     cont,
+	}
+
+	host := opts.URL
+	if u, err := neturl.Parse(opts.URL); err == nil && u.Host != "" {
+		host = u.Host
 	}
+	g.throttle, g.release = HostThrottle(host, opts.Rate)
 
 	basicOptions := libgobuster.BasicHTTPOptions{
 		Proxy:     opts.Proxy,
@@ -115,12 +186,23 @@ func NewGobusterDir(cont context.Context, opts *OptionsDir) (*GobusterDir, error
 
 	h, err := libgobuster.NewHTTPClient(cont, &httpOpts)
 	if err != nil {
+		g.release()
 		return nil, err
 	}
 	g.http = h
 	return &g, nil
 }
 
+// Close gives up the share of the buster in the rate limit of the host
+func (d *GobusterDir) Close() {
+	d.release()
+}
+
+// OnHit sets a function called for every result as soon as it is found
+func (d *GobusterDir) OnHit(f func(GoBusterResult)) {
+	d.onHit = f
+}
+
 // PreRun is the pre run implementation of gobusterdir
 func (d *GobusterDir) PreRun() error {
 	// add trailing slash
@@ -133,30 +215,53 @@ func (d *GobusterDir) PreRun() error {
 		return fmt.Errorf("unable to connect to %s: %v", d.options.URL, err)
 	}
 
-	guid := uuid.New()
-	url := fmt.Sprintf("%s%s", d.options.URL, guid)
-	wildcardResp, _, _, _, err := d.get(url)
-	if err != nil {
-		return err
+	if d.options.StatusCodesParsed.Length() == 0 {
+		return fmt.Errorf("StatusCodes is not set which should not happen")
 	}
 
-	if d.options.StatusCodesParsed.Length() > 0 {
-		if d.options.StatusCodesParsed.Contains(*wildcardResp) && !d.options.WildcardForced {
-			return fmt.Errorf("the server returns a status code that matches the provided options for non existing urls. %s => %d", url, *wildcardResp)
+	// Learn what non existing paths look like, a server may answer them all
+	samples := []string{uuid.New().String(), uuid.New().String(), uuid.New().String() + "/"}
+	for _, ext := range d.options.Extensions {
+		samples = append(samples, uuid.New().String()+"."+ext)
+	}
+
+	baseline := &WildcardBaseline{}
+	for _, sample := range samples {
+		url := fmt.Sprintf("%s%s", d.options.URL, sample)
+		wildcardResp, _, _, body, err := d.get(url)
+		if err != nil {
+			return err
 		}
-	} else {
-		return fmt.Errorf("StatusCodes is not set which should not happen")
+		if wildcardResp == nil || !d.options.StatusCodesParsed.Contains(*wildcardResp) {
+			continue
+		}
+		baseline.Samples = append(baseline.Samples, sample)
+		baseline.add(NewResponseFingerprint(*wildcardResp, body, sample))
+	}
+	if len(baseline.Fingerprints) > 0 {
+		d.baseline = baseline
 	}
 
 	return nil
 }
 
+// Baseline returns the wildcard responses learned by PreRun and how many hits they filtered,
+// nil if the server does not answer non existing paths
+func (d *GobusterDir) Baseline() *WildcardBaseline {
+	if d.baseline == nil {
+		return nil
+	}
+	b := *d.baseline
+	b.Dropped = int(atomic.LoadInt32(&d.dropped))
+	return &b
+}
+
 // RunWord is the process implementation of gobusterdir
 func (d *GobusterDir) RunWord(word string) (*GoBusterResult, error) {
 
 	// Try the DIR first
 	url := fmt.Sprintf("%s%s", d.options.URL, word)
-	dirResp, dirSize, _, bodyBytes, err := d.get(url)
+	dirResp, dirSize, header, bodyBytes, elapsed, err := d.timedGet(url)
 	body := string(bodyBytes)
 	if err != nil {
 		return nil, err
@@ -172,18 +277,165 @@ func (d *GobusterDir) RunWord(word string) (*GoBusterResult, error) {
 			return nil, fmt.Errorf("StatusCodes is not set which should not happen")
 		}
 		if resultStatus && (d.options.ExcludeText == "" || !strings.Contains(body, d.options.ExcludeText)) {
-			return d.ResultToStruct(word, *dirResp, dirSize), nil
+			if !d.options.WildcardForced && d.baseline.Matches(NewResponseFingerprint(*dirResp, bodyBytes, word)) {
+				atomic.AddInt32(&d.dropped, 1)
+				return nil, nil
+			}
+			res := d.ResultToStruct(word, *dirResp, dirSize)
+			res.isDir = isDirectory(word, *dirResp, header)
+			res.describe(header, bodyBytes, elapsed)
+			return res, nil
 		}
 	}
 	return nil, nil
 }
 
-// Run processes a wordlist and calls RunWord repeatitvely
-func (d *GobusterDir) Run(wordlist []string) []GoBusterResult {
-	var ret []GoBusterResult
+// describe fills the details of a result from its response
+func (r *GoBusterResult) describe(header http.Header, body []byte, elapsed time.Duration) {
+	if r.StatusCode >= 300 && r.StatusCode < 400 {
+		r.Location = header.Get("Location")
+	}
+	r.ContentType = header.Get("Content-Type")
+	r.Title = ExtractTitle(body)
+	r.Words = len(bytes.Fields(body))
+	r.Lines = bytes.Count(body, []byte("\n")) + 1
+	r.ResponseTime = elapsed.Milliseconds()
+	hash := sha1.Sum(body)
+	r.Hash = hex.EncodeToString(hash[:])
+}
+
+// ExtractTitle returns the title of an HTML page, empty if it has none
+func ExtractTitle(body []byte) string {
+	matches := titleRegexp.FindSubmatch(body)
+	if matches == nil {
+		return ""
+	}
+	title := strings.Join(strings.Fields(html.UnescapeString(string(matches[1]))), " ")
+	if runes := []rune(title); len(runes) > maxTitleLength {
+		title = string(runes[:maxTitleLength])
+	}
+	return title
+}
+
+// isDirectory tells whether a hit is a directory that can be recursed into
+func isDirectory(path string, statusCode int, header http.Header) bool {
+	if strings.HasSuffix(path, "/") {
+		return true
+	}
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return false
+	}
+	location, err := neturl.Parse(header.Get("Location"))
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(location.Path, "/"+strings.TrimPrefix(path, "/")+"/")
+}
+
+// expand returns the paths to try for every word of a wordlist under a directory, under the base path when
+// parent is empty
+func (d *GobusterDir) expand(wordlist []string, parent string, depth int) []candidate {
+	var ret []candidate
+	prefix := ""
+	if dir := strings.Trim(d.options.Base, "/"); dir != "" {
+		prefix = dir + "/"
+	}
+	if parent != "" {
+		prefix = strings.TrimSuffix(parent, "/") + "/"
+	}
 	for _, word := range wordlist {
-		res, err := d.RunWord(word)
-		if err == nil && res != nil {
+		path := prefix + word
+		ret = append(ret, candidate{path, parent, depth})
+		for _, ext := range d.options.Extensions {
+			ret = append(ret, candidate{path + "." + ext, parent, depth})
+		}
+	}
+	return ret
+}
+
+// Run processes a wordlist, then recurses into the directories found up to the configured depth,
+// results are returned level by level in the order of the wordlist
+func (d *GobusterDir) Run(ctx context.Context, wordlist []string) []GoBusterResult {
+	var ret []GoBusterResult
+
+	level := d.expand(wordlist, "", 0)
+	for depth := 0; len(level) > 0 && ctx.Err() == nil; depth++ {
+		found := d.scan(ctx, level)
+		ret = append(ret, found...)
+
+		var next []candidate
+		var backups []candidate
+		for _, res := range found {
+			if res.isDir {
+				if depth < d.options.Depth {
+					next = append(next, d.expand(wordlist, res.Path, depth+1)...)
+				}
+				continue
+			}
+			if d.options.Backups {
+				for _, suffix := range backupSuffixes {
+					backups = append(backups, candidate{res.Path + suffix, res.ParentPath, res.Depth})
+				}
+			}
+		}
+		if len(backups) > 0 {
+			ret = append(ret, d.scan(ctx, backups)...)
+		}
+		level = next
+	}
+	return ret
+}
+
+// scan tries candidates with a pool of workers calling RunWord until ctx is done,
+// results are returned in the order of the candidates
+func (d *GobusterDir) scan(ctx context.Context, candidates []candidate) []GoBusterResult {
+	var ret []GoBusterResult
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	found := make([]*GoBusterResult, len(candidates))
+	words := make(chan int)
+
+	threads := d.options.Threads
+	if threads <= 0 {
+		threads = 1
+	}
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range words {
+				res, err := d.RunWord(candidates[idx].path)
+				if err != nil || res == nil {
+					continue
+				}
+				res.ParentPath = candidates[idx].parent
+				res.Depth = candidates[idx].depth
+				found[idx] = res
+				if d.onHit != nil {
+					mu.Lock()
+					d.onHit(*res)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for idx := range candidates {
+		select {
+		case words <- idx:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(words)
+	wg.Wait()
+
+	for _, res := range found {
+		if res != nil {
 			ret = append(ret, *res)
 		}
 	}
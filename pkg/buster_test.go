@@ -0,0 +1,86 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestBuster(t *testing.T, url string, base string, depth int) *GobusterDir {
+	opts := NewOptionsDir("200,204,301,302,307,401,403", nil, false, "")
+	opts.URL = url
+	opts.Timeout = 5 * time.Second
+	opts.UserAgent = "test"
+	opts.Base = base
+	opts.Depth = depth
+	opts.Extensions = []string{"php"}
+	buster, err := NewGobusterDir(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(buster.Close)
+	return buster
+}
+
+func TestGobusterDirExpand(t *testing.T) {
+	buster := newTestBuster(t, "http://127.0.0.1/", "/app/", 1)
+
+	tests := []struct {
+		parent string
+		depth  int
+		want   []string
+	}{
+		{"", 0, []string{"app/admin", "app/admin.php"}},
+		{"app/admin/", 1, []string{"app/admin/admin", "app/admin/admin.php"}},
+		{"app/admin", 1, []string{"app/admin/admin", "app/admin/admin.php"}},
+	}
+	for _, tt := range tests {
+		got := buster.expand([]string{"admin"}, tt.parent, tt.depth)
+		if len(got) != len(tt.want) {
+			t.Fatalf("expand under %q gave %v, want %v", tt.parent, got, tt.want)
+		}
+		for idx := range got {
+			if got[idx].path != tt.want[idx] || got[idx].parent != tt.parent || got[idx].depth != tt.depth {
+				t.Errorf("expand under %q gave %v, want path %q", tt.parent, got[idx], tt.want[idx])
+			}
+		}
+	}
+}
+
+func TestGobusterDirRunBaseDepth(t *testing.T) {
+	pages := map[string]bool{"/": true, "/app/admin/": true, "/app/admin/secret": true}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !pages[r.URL.Path] {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	buster := newTestBuster(t, server.URL, "app", 1)
+	buster.options.Extensions = nil
+	err := buster.PreRun()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := buster.Run(context.Background(), []string{"admin/", "secret"})
+	want := []struct {
+		path   string
+		parent string
+		depth  int
+	}{
+		{"app/admin/", "", 0},
+		{"app/admin/secret", "app/admin/", 1},
+	}
+	if len(res) != len(want) {
+		t.Fatalf("got %d results, want %d : %v", len(res), len(want), res)
+	}
+	for idx, w := range want {
+		if res[idx].Path != w.path || res[idx].ParentPath != w.parent || res[idx].Depth != w.depth {
+			t.Errorf("result %d is %s under %q at depth %d, want %s under %q at depth %d",
+				idx, res[idx].Path, res[idx].ParentPath, res[idx].Depth, w.path, w.parent, w.depth)
+		}
+	}
+}
@@ -6,11 +6,11 @@ import (
 	"fmt"
 	"log"
 	"net"
-	"strings"
-	"time"
+	"sync"
 
 	"github.com/OJ/gobuster/v3/libgobuster"
 	"github.com/google/uuid"
+	"golang.org/x/net/dns/dnsmessage"
 )
 
 // OptionsDNS holds all options for the dns plugin
@@ -19,13 +19,21 @@ type OptionsDNS struct {
 	ShowIPs        bool
 	ShowCNAME      bool
 	WildcardForced bool
-	Resolver       string
-	Timeout        time.Duration
+	// Resolvers answer the lookups in turn
+	Resolvers *ResolverPool
+	// Dangling looks up through the resolvers the CNAME of the names which do not resolve, a name with a CNAME to a
+	// target which does not exist is found too
+	Dangling bool
+	// Threads is the number of concurrent lookups, the resolvers of the pool limit the rate of the queries
+	Threads int
 }
 
-// NewOptionsDNS returns a new initialized OptionsDNS
-func NewOptionsDNS(domain string, isWildcard bool, resolver string) *OptionsDNS {
-	return &OptionsDNS{Domain: domain, WildcardForced: isWildcard, Resolver: resolver}
+// NewOptionsDNS returns a new initialized OptionsDNS, the default resolver pool is used when resolvers is nil
+func NewOptionsDNS(domain string, isWildcard bool, resolvers *ResolverPool) *OptionsDNS {
+	if resolvers == nil {
+		resolvers = DefaultResolverPool()
+	}
+	return &OptionsDNS{Domain: domain, WildcardForced: isWildcard, Resolvers: resolvers, Threads: 1}
 }
 
 // ErrWildcard is returned if a wildcard response is found
@@ -33,7 +41,6 @@ var ErrWildcard = errors.New("wildcard found")
 
 // GobusterDNS is the main type to implement the interface
 type GobusterDNS struct {
-	resolver    *net.Resolver
 	globalopts  *libgobuster.Options
 	options     *OptionsDNS
 	isWildcard  bool
@@ -43,10 +50,7 @@ type GobusterDNS struct {
 func newCustomDialer(server string) func(ctx context.Context, network, address string) (net.Conn, error) {
 	return func(ctx context.Context, network, address string) (net.Conn, error) {
 		d := net.Dialer{}
-		if !strings.Contains(server, ":") {
-			server = fmt.Sprintf("%s:53", server)
-		}
-		return d.DialContext(ctx, "udp", server)
+		return d.DialContext(ctx, "udp", resolverAddress(server))
 	}
 }
 
@@ -57,31 +61,22 @@ func NewGobusterDNS(opts *OptionsDNS) (*GobusterDNS, error) {
 	}
 
 	globalopts := &libgobuster.Options{}
-	globalopts.Threads = 5
+	globalopts.Threads = opts.Threads
 	globalopts.Quiet = true
 
-	resolver := net.DefaultResolver
-	if opts.Resolver != "" {
-		resolver = &net.Resolver{
-			PreferGo: true,
-			Dial:     newCustomDialer(opts.Resolver),
-		}
-	}
-
 	g := GobusterDNS{
 		options:     opts,
 		globalopts:  globalopts,
 		wildcardIps: libgobuster.NewStringSet(),
-		resolver:    resolver,
 	}
 	return &g, nil
 }
 
 // PreRun is the pre run implementation of gobusterdns
-func (d *GobusterDNS) PreRun() error {
+func (d *GobusterDNS) PreRun(ctx context.Context) error {
 	// Resolve a subdomain sthat probably shouldn't exist
 	guid := uuid.New()
-	wildcardIps, err := d.dnsLookup(fmt.Sprintf("%s.%s", guid, d.options.Domain))
+	wildcardIps, err := d.dnsLookup(ctx, fmt.Sprintf("%s.%s", guid, d.options.Domain))
 	if err == nil {
 		d.isWildcard = true
 		d.wildcardIps.AddRange(wildcardIps)
@@ -92,7 +87,7 @@ func (d *GobusterDNS) PreRun() error {
 
 	if !d.globalopts.Quiet {
 		// Provide a warning if the base domain doesn't resolve (in case of typo)
-		_, err = d.dnsLookup(d.options.Domain)
+		_, err = d.dnsLookup(ctx, d.options.Domain)
 		if err != nil {
 			// Not an error, just a warning. Eg. `yp.to` doesn't resolve, but `cr.yp.to` does!
 			log.Printf("[-] Unable to validate base domain: %s (%v)", d.options.Domain, err)
@@ -103,40 +98,70 @@ func (d *GobusterDNS) PreRun() error {
 }
 
 // RunWord is the process implementation of gobusterdns
-func (d *GobusterDNS) RunWord(word string) error {
+func (d *GobusterDNS) RunWord(ctx context.Context, word string) error {
 	subdomain := fmt.Sprintf("%s.%s", word, d.options.Domain)
-	ips, err := d.dnsLookup(subdomain)
+	ips, err := d.dnsLookup(ctx, subdomain)
 	if err == nil {
 		if d.wildcardIps.ContainsAny(ips) {
 			return fmt.Errorf("wildcard domain")
 		}
 		return nil
 	}
+	var dnsErr *net.DNSError
+	if d.options.Dangling && errors.As(err, &dnsErr) && dnsErr.IsNotFound && d.dangling(ctx, subdomain) {
+		return nil
+	}
 	return err
 }
 
-// Run is the process implementation of wordlist gobusterdns
-func (d *GobusterDNS) Run(wordlist []string) []string {
-	var err error
+// dangling tells whether a name which does not resolve has a CNAME, its target does not exist
+func (d *GobusterDNS) dangling(ctx context.Context, name string) bool {
+	records, err := d.options.Resolvers.Query(ctx, name, dnsmessage.TypeCNAME)
+	return err == nil && len(RecordValues(records, "CNAME")) > 0
+}
+
+// Run is the process implementation of wordlist gobusterdns, the words found are returned in the order of the wordlist,
+// it stops early when ctx is done
+func (d *GobusterDNS) Run(ctx context.Context, wordlist []string) []string {
+	found := make([]bool, len(wordlist))
+	words := make(chan int)
+	var wg sync.WaitGroup
+
+	threads := d.globalopts.Threads
+	if threads <= 0 {
+		threads = 1
+	}
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range words {
+				found[idx] = d.RunWord(ctx, wordlist[idx]) == nil
+			}
+		}()
+	}
+
+feed:
+	for idx := range wordlist {
+		select {
+		case <-ctx.Done():
+			break feed
+		case words <- idx:
+		}
+	}
+	close(words)
+	wg.Wait()
+
 	domains := make([]string, 0)
-	for _, word := range wordlist {
-		err = d.RunWord(word)
-		if err == nil {
-			domains = append(domains, word)
+	for idx, ok := range found {
+		if ok {
+			domains = append(domains, wordlist[idx])
 		}
 	}
 	return domains
 }
 
-func (d *GobusterDNS) dnsLookup(domain string) ([]string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), d.options.Timeout)
-	defer cancel()
-	return d.resolver.LookupHost(ctx, domain)
-}
-
-func (d *GobusterDNS) dnsLookupCname(domain string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), d.options.Timeout)
-	defer cancel()
-	time.Sleep(time.Second)
-	return d.resolver.LookupCNAME(ctx, domain)
+// dnsLookup resolves a name with the resolvers of the pool
+func (d *GobusterDNS) dnsLookup(ctx context.Context, domain string) ([]string, error) {
+	return d.options.Resolvers.LookupHost(ctx, domain)
 }
@@ -0,0 +1,80 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// discoveryPorts : ports probed to tell whether a host is up
+var discoveryPorts = []int{80, 443, 22, 445, 3389, 8080, 21, 25}
+
+// HostDiscovery : struct for finding live hosts
+type HostDiscovery struct {
+	timeout time.Duration
+	threads int
+}
+
+// NewHostDiscovery : returns new HostDiscovery struct
+func NewHostDiscovery(timeout time.Duration, threads int) *HostDiscovery {
+	return &HostDiscovery{timeout, threads}
+}
+
+// IsAlive : checks if a host answers on any of the discovery ports, a refused connection means it is up
+func (h HostDiscovery) IsAlive(ctx context.Context, host string) bool {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	alive := make(chan bool, len(discoveryPorts))
+	for _, port := range discoveryPorts {
+		go func(port int) {
+			d := net.Dialer{Timeout: h.timeout}
+			conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+			if err == nil {
+				conn.Close()
+			}
+			alive <- err == nil || errors.Is(err, syscall.ECONNREFUSED)
+		}(port)
+	}
+
+	for range discoveryPorts {
+		if <-alive {
+			return true
+		}
+	}
+	return false
+}
+
+// Run : returns the live hosts from a given list, it stops early when ctx is done
+func (h HostDiscovery) Run(ctx context.Context, hosts []string) []string {
+	found := make([]bool, len(hosts))
+	var wg sync.WaitGroup
+	sem := make(chan bool, h.threads)
+loop:
+	for idx, host := range hosts {
+		select {
+		case <-ctx.Done():
+			break loop
+		case sem <- true:
+		}
+		wg.Add(1)
+		go func(idx int, host string) {
+			defer wg.Done()
+			found[idx] = h.IsAlive(ctx, host)
+			<-sem
+		}(idx, host)
+	}
+	wg.Wait()
+
+	rv := []string{}
+	for idx, host := range hosts {
+		if found[idx] {
+			rv = append(rv, host)
+		}
+	}
+	return rv
+}
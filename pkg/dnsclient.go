@@ -0,0 +1,480 @@
+package pkg
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSRecord : a DNS resource record
+type DNSRecord struct {
+	Name  string `bson:"name" json:"name"`
+	Type  string `bson:"type" json:"type"`
+	TTL   uint32 `bson:"ttl" json:"ttl"`
+	Value string `bson:"value" json:"value"`
+}
+
+// RecordTypes : types of the records collected for a name
+var RecordTypes = []dnsmessage.Type{
+	dnsmessage.TypeA,
+	dnsmessage.TypeAAAA,
+	dnsmessage.TypeCNAME,
+	dnsmessage.TypeMX,
+	dnsmessage.TypeTXT,
+	dnsmessage.TypeNS,
+	dnsmessage.TypeSOA,
+	dnsmessage.TypeSRV,
+}
+
+// srvServices : SRV names looked up under a domain
+var srvServices = []string{
+	"_sip._tcp", "_sip._udp", "_sips._tcp", "_xmpp-server._tcp", "_xmpp-client._tcp",
+	"_ldap._tcp", "_kerberos._tcp", "_kerberos._udp", "_autodiscover._tcp", "_caldav._tcp",
+	"_carddav._tcp", "_imaps._tcp", "_submission._tcp", "_minecraft._tcp",
+}
+
+// DNSClient : raw DNS client querying a single server, for the records net.Resolver does not give
+type DNSClient struct {
+	server  string
+	timeout time.Duration
+}
+
+// resolverAddress : returns the address of a DNS server, port 53 is used when it is not given
+func resolverAddress(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(strings.TrimSuffix(strings.TrimPrefix(server, "["), "]"), "53")
+}
+
+// systemResolver : returns the first nameserver of /etc/resolv.conf
+func systemResolver() (string, error) {
+	f, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return fields[1], nil
+		}
+	}
+	return "", fmt.Errorf("no nameserver in /etc/resolv.conf")
+}
+
+// NewDNSClient : returns new DNSClient querying server, the system resolver when server is empty
+func NewDNSClient(server string, timeout time.Duration) (*DNSClient, error) {
+	if server == "" {
+		var err error
+		server, err = systemResolver()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &DNSClient{resolverAddress(server), timeout}, nil
+}
+
+// Server : returns the address of the server queried
+func (c DNSClient) Server() string {
+	return c.server
+}
+
+func newQuery(name string, qtype dnsmessage.Type) ([]byte, uint16, error) {
+	fqdn, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return nil, 0, err
+	}
+	var random [2]byte
+	_, err = rand.Read(random[:])
+	if err != nil {
+		return nil, 0, err
+	}
+	id := binary.BigEndian.Uint16(random[:])
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: fqdn, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := msg.Pack()
+	return packed, id, err
+}
+
+// exchange : sends a query over UDP, or TCP if the answer is truncated, and returns the raw answer
+func (c DNSClient) exchange(ctx context.Context, query []byte, id uint16) ([]byte, error) {
+	answer, err := c.exchangeUDP(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	var p dnsmessage.Parser
+	h, err := p.Start(answer)
+	if err != nil {
+		return nil, err
+	}
+	if !h.Truncated {
+		return answer, nil
+	}
+
+	conn, err := c.dial(ctx, "tcp")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = writeTCPMessage(conn, query)
+	if err != nil {
+		return nil, err
+	}
+	return readTCPMessage(conn)
+}
+
+func (c DNSClient) dial(ctx context.Context, network string) (net.Conn, error) {
+	d := net.Dialer{Timeout: c.timeout}
+	conn, err := d.DialContext(ctx, network, c.server)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(c.deadline(ctx))
+	return conn, nil
+}
+
+// deadline : returns the deadline of an exchange, the one of ctx if it is earlier
+func (c DNSClient) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	return deadline
+}
+
+func (c DNSClient) exchangeUDP(ctx context.Context, query []byte, id uint16) ([]byte, error) {
+	conn, err := c.dial(ctx, "udp")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write(query)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// Answers to other queries are ignored
+		if n >= 2 && binary.BigEndian.Uint16(buf) == id {
+			return buf[:n], nil
+		}
+	}
+}
+
+func writeTCPMessage(conn net.Conn, msg []byte) error {
+	buf := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	copy(buf[2:], msg)
+	_, err := conn.Write(buf)
+	return err
+}
+
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var length [2]byte
+	_, err := io.ReadFull(r, length[:])
+	if err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+	_, err = io.ReadFull(r, msg)
+	return msg, err
+}
+
+// Query : returns the records of the answer to a query, a name which does not exist has no record
+func (c DNSClient) Query(ctx context.Context, name string, qtype dnsmessage.Type) ([]DNSRecord, error) {
+	_, records, err := c.query(ctx, name, qtype)
+	return records, err
+}
+
+// Exists : tells whether a name exists, that is whether the server does not answer NXDOMAIN for it
+func (c DNSClient) Exists(ctx context.Context, name string) (bool, error) {
+	rcode, _, err := c.query(ctx, name, dnsmessage.TypeA)
+	return rcode != dnsmessage.RCodeNameError, err
+}
+
+func (c DNSClient) query(ctx context.Context, name string, qtype dnsmessage.Type) (dnsmessage.RCode, []DNSRecord, error) {
+	query, id, err := newQuery(name, qtype)
+	if err != nil {
+		return 0, nil, err
+	}
+	answer, err := c.exchange(ctx, query, id)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var p dnsmessage.Parser
+	h, err := p.Start(answer)
+	if err != nil {
+		return 0, nil, err
+	}
+	if h.RCode != dnsmessage.RCodeSuccess && h.RCode != dnsmessage.RCodeNameError {
+		return h.RCode, nil, fmt.Errorf("%s answered %s for %s", c.server, h.RCode, name)
+	}
+	err = p.SkipAllQuestions()
+	if err != nil {
+		return h.RCode, nil, err
+	}
+	records, err := parseRecords(&p)
+	return h.RCode, records, err
+}
+
+// parseRecords : returns the answer records of a message, records of unsupported types are skipped
+func parseRecords(p *dnsmessage.Parser) ([]DNSRecord, error) {
+	records := make([]DNSRecord, 0)
+	for {
+		h, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+
+		value, err := parseRecordValue(p, h.Type)
+		if err != nil {
+			return records, err
+		}
+		if value == "" {
+			continue
+		}
+		records = append(records, DNSRecord{
+			Name:  strings.TrimSuffix(h.Name.String(), "."),
+			Type:  strings.TrimPrefix(h.Type.String(), "Type"),
+			TTL:   h.TTL,
+			Value: value,
+		})
+	}
+}
+
+// parseRecordValue : returns the value of the record being parsed, empty if its type is not supported
+func parseRecordValue(p *dnsmessage.Parser, rtype dnsmessage.Type) (string, error) {
+	switch rtype {
+	case dnsmessage.TypeA:
+		r, err := p.AResource()
+		return net.IP(r.A[:]).String(), err
+	case dnsmessage.TypeAAAA:
+		r, err := p.AAAAResource()
+		return net.IP(r.AAAA[:]).String(), err
+	case dnsmessage.TypeCNAME:
+		r, err := p.CNAMEResource()
+		return strings.TrimSuffix(r.CNAME.String(), "."), err
+	case dnsmessage.TypeMX:
+		r, err := p.MXResource()
+		return fmt.Sprintf("%d %s", r.Pref, strings.TrimSuffix(r.MX.String(), ".")), err
+	case dnsmessage.TypeTXT:
+		r, err := p.TXTResource()
+		return strings.Join(r.TXT, ""), err
+	case dnsmessage.TypeNS:
+		r, err := p.NSResource()
+		return strings.TrimSuffix(r.NS.String(), "."), err
+	case dnsmessage.TypePTR:
+		r, err := p.PTRResource()
+		return strings.TrimSuffix(r.PTR.String(), "."), err
+	case dnsmessage.TypeSOA:
+		r, err := p.SOAResource()
+		return fmt.Sprintf("%s %s %d %d %d %d %d", strings.TrimSuffix(r.NS.String(), "."), strings.TrimSuffix(r.MBox.String(), "."),
+			r.Serial, r.Refresh, r.Retry, r.Expire, r.MinTTL), err
+	case dnsmessage.TypeSRV:
+		r, err := p.SRVResource()
+		return fmt.Sprintf("%d %d %d %s", r.Priority, r.Weight, r.Port, strings.TrimSuffix(r.Target.String(), ".")), err
+	}
+	return "", p.SkipAnswer()
+}
+
+// Lookup : returns the records of every type of RecordTypes for a name, the records of common SRV services
+// under the name are added when withServices is set
+func (c DNSClient) Lookup(ctx context.Context, name string, withServices bool) ([]DNSRecord, error) {
+	return lookupRecords(ctx, c.Query, name, withServices)
+}
+
+// lookupRecords : returns the records of every type of RecordTypes for a name sending the queries with query
+func lookupRecords(
+	ctx context.Context,
+	query func(ctx context.Context, name string, qtype dnsmessage.Type) ([]DNSRecord, error),
+	name string,
+	withServices bool,
+) ([]DNSRecord, error) {
+	records := make([]DNSRecord, 0)
+	seen := make(map[string]bool)
+	add := func(rs []DNSRecord) {
+		for _, r := range rs {
+			key := r.Name + " " + r.Type + " " + r.Value
+			if !seen[key] {
+				seen[key] = true
+				records = append(records, r)
+			}
+		}
+	}
+
+	var lastErr error
+	failed := 0
+	for _, qtype := range RecordTypes {
+		rs, err := query(ctx, name, qtype)
+		if err != nil {
+			lastErr = err
+			failed++
+			continue
+		}
+		add(rs)
+	}
+	if failed == len(RecordTypes) {
+		return records, lastErr
+	}
+
+	if withServices {
+		for _, service := range srvServices {
+			rs, err := query(ctx, service+"."+name, dnsmessage.TypeSRV)
+			if err == nil {
+				add(rs)
+			}
+		}
+	}
+	return records, ctx.Err()
+}
+
+// RecordValues : returns the values of the records of a type
+func RecordValues(records []DNSRecord, rtype string) []string {
+	values := make([]string, 0)
+	for _, r := range records {
+		if r.Type == rtype {
+			values = append(values, r.Value)
+		}
+	}
+	return values
+}
+
+// Transfer : returns the records of a zone transferred (AXFR) from the server, it fails if the server refuses it
+func (c DNSClient) Transfer(ctx context.Context, zone string) ([]DNSRecord, error) {
+	query, id, err := newQuery(zone, dnsmessage.TypeAXFR)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := c.dial(ctx, "tcp")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = writeTCPMessage(conn, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// The zone is sent in as many messages as needed, between two copies of its SOA record
+	records := make([]DNSRecord, 0)
+	soas := 0
+	for soas < 2 {
+		conn.SetDeadline(c.deadline(ctx))
+		answer, err := readTCPMessage(conn)
+		if err != nil {
+			return nil, err
+		}
+
+		var p dnsmessage.Parser
+		h, err := p.Start(answer)
+		if err != nil {
+			return nil, err
+		}
+		if h.ID != id {
+			return nil, fmt.Errorf("%s answered another query", c.server)
+		}
+		if h.RCode != dnsmessage.RCodeSuccess {
+			return nil, fmt.Errorf("%s refused the transfer of %s : %s", c.server, zone, h.RCode)
+		}
+		err = p.SkipAllQuestions()
+		if err != nil {
+			return nil, err
+		}
+		rs, err := parseRecords(&p)
+		if err != nil {
+			return nil, err
+		}
+		if len(rs) == 0 || (len(records) == 0 && rs[0].Type != "SOA") {
+			return nil, fmt.Errorf("%s refused the transfer of %s", c.server, zone)
+		}
+
+		for _, r := range rs {
+			if r.Type == "SOA" {
+				soas++
+				if soas == 2 {
+					break
+				}
+			}
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+// ZoneTransfer : outcome of a zone transfer attempt against a nameserver
+type ZoneTransfer struct {
+	Nameserver string      `bson:"nameserver" json:"nameserver"`
+	Address    string      `bson:"address" json:"address"`
+	Success    bool        `bson:"success" json:"success"`
+	Records    []DNSRecord `bson:"records" json:"records"`
+	Err        string      `bson:"err" json:"err"`
+}
+
+// NameServers : returns the nameservers of a domain
+func (c DNSClient) NameServers(ctx context.Context, domain string) ([]string, error) {
+	records, err := c.Query(ctx, domain, dnsmessage.TypeNS)
+	if err != nil {
+		return nil, err
+	}
+	return RecordValues(records, "NS"), nil
+}
+
+// TransferZone : attempts a zone transfer from every address of every nameserver of a domain
+func (c DNSClient) TransferZone(ctx context.Context, domain string) ([]ZoneTransfer, error) {
+	nameservers, err := c.NameServers(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := make([]ZoneTransfer, 0)
+	for _, ns := range nameservers {
+		addresses := make([]string, 0)
+		for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+			records, err := c.Query(ctx, ns, qtype)
+			if err == nil {
+				addresses = append(addresses, RecordValues(records, strings.TrimPrefix(qtype.String(), "Type"))...)
+			}
+		}
+		if len(addresses) == 0 {
+			transfers = append(transfers, ZoneTransfer{Nameserver: ns, Err: "nameserver does not resolve"})
+		}
+
+		for _, address := range addresses {
+			if ctx.Err() != nil {
+				return transfers, ctx.Err()
+			}
+			t := ZoneTransfer{Nameserver: ns, Address: address}
+			server := &DNSClient{resolverAddress(address), c.timeout}
+			t.Records, err = server.Transfer(ctx, domain)
+			t.Success = err == nil
+			if err != nil {
+				t.Err = err.Error()
+			}
+			transfers = append(transfers, t)
+		}
+	}
+	return transfers, nil
+}
@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// zoneServer : serves over TCP the transfer of example.test in three messages and refuses any other zone
+func zoneServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	zone := dnsmessage.MustNewName("example.test.")
+	hdr := func(name string, rtype dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: rtype, Class: dnsmessage.ClassINET, TTL: 300}
+	}
+	soa := dnsmessage.Resource{Header: hdr("example.test.", dnsmessage.TypeSOA), Body: &dnsmessage.SOAResource{
+		NS: dnsmessage.MustNewName("ns1.example.test."), MBox: dnsmessage.MustNewName("admin.example.test."),
+		Serial: 1, Refresh: 3600, Retry: 600, Expire: 86400, MinTTL: 300,
+	}}
+	messages := [][]dnsmessage.Resource{
+		{soa, {Header: hdr("www.example.test.", dnsmessage.TypeA), Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}}},
+		{
+			{Header: hdr("mail.example.test.", dnsmessage.TypeA), Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}}},
+			{Header: hdr("dev.example.test.", dnsmessage.TypeCNAME), Body: &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("www.example.test.")}},
+		},
+		{soa},
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				query, err := readTCPMessage(conn)
+				if err != nil {
+					return
+				}
+				var m dnsmessage.Message
+				if m.Unpack(query) != nil || len(m.Questions) != 1 {
+					return
+				}
+				m.Header.Response = true
+				if m.Questions[0].Name != zone || m.Questions[0].Type != dnsmessage.TypeAXFR {
+					m.Header.RCode = dnsmessage.RCodeRefused
+					answer, _ := m.Pack()
+					writeTCPMessage(conn, answer)
+					return
+				}
+				for _, answers := range messages {
+					m.Answers = answers
+					answer, err := m.Pack()
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					writeTCPMessage(conn, answer)
+				}
+			}(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestDNSClientTransfer(t *testing.T) {
+	client, err := NewDNSClient(zoneServer(t), 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := client.Transfer(context.Background(), "example.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("got %d records, want 4 : %v", len(records), records)
+	}
+	if records[0].Type != "SOA" || records[0].Name != "example.test" {
+		t.Errorf("first record is %v, want the SOA of the zone", records[0])
+	}
+	if got := RecordValues(records, "A"); len(got) != 2 || got[0] != "10.0.0.1" || got[1] != "10.0.0.2" {
+		t.Errorf("got A records %v", got)
+	}
+	if got := RecordValues(records, "CNAME"); len(got) != 1 || got[0] != "www.example.test" {
+		t.Errorf("got CNAME records %v", got)
+	}
+
+	_, err = client.Transfer(context.Background(), "other.test")
+	if err == nil {
+		t.Error("transfer of a refused zone succeeded")
+	}
+}
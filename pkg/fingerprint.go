@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/url"
+	"path"
+)
+
+// ResponseFingerprint describes a response so that it can be compared to others
+type ResponseFingerprint struct {
+	StatusCode int    `bson:"statusCode" json:"statusCode"`
+	Size       int    `bson:"size" json:"size"`
+	Words      int    `bson:"words" json:"words"`
+	Lines      int    `bson:"lines" json:"lines"`
+	Hash       string `bson:"hash" json:"hash"`
+}
+
+// WildcardBaseline is what non existing paths look like on a server answering them all
+type WildcardBaseline struct {
+	Samples      []string              `bson:"samples" json:"samples"`
+	Fingerprints []ResponseFingerprint `bson:"fingerprints" json:"fingerprints"`
+	Dropped      int                   `bson:"dropped" json:"dropped"`
+}
+
+// fingerprintTolerance is the relative size difference under which two responses are deemed similar
+const fingerprintTolerance = 0.05
+
+// NewResponseFingerprint returns the fingerprint of a response to a path,
+// occurrences of the path are removed from the body as servers often reflect it
+func NewResponseFingerprint(statusCode int, body []byte, requested string) ResponseFingerprint {
+	normalized := body
+	for _, s := range []string{requested, url.PathEscape(requested), path.Base(requested)} {
+		if s != "" && s != "." && s != "/" {
+			normalized = bytes.ReplaceAll(normalized, []byte(s), nil)
+		}
+	}
+
+	hash := sha1.Sum(normalized)
+	return ResponseFingerprint{
+		StatusCode: statusCode,
+		Size:       len(normalized),
+		Words:      len(bytes.Fields(normalized)),
+		Lines:      bytes.Count(normalized, []byte("\n")) + 1,
+		Hash:       hex.EncodeToString(hash[:]),
+	}
+}
+
+// Matches tells whether two fingerprints are likely the same page
+func (f ResponseFingerprint) Matches(o ResponseFingerprint) bool {
+	if f.StatusCode != o.StatusCode {
+		return false
+	}
+	if f.Hash == o.Hash {
+		return true
+	}
+
+	// Dynamic content (dates, tokens...) changes the hash but hardly the shape of the page
+	max := f.Size
+	if o.Size > max {
+		max = o.Size
+	}
+	return abs(f.Size-o.Size) <= int(float64(max)*fingerprintTolerance) &&
+		abs(f.Words-o.Words) <= 1 &&
+		abs(f.Lines-o.Lines) <= 1
+}
+
+// Matches tells whether a response looks like the wildcard response
+func (b *WildcardBaseline) Matches(f ResponseFingerprint) bool {
+	if b == nil {
+		return false
+	}
+	for _, fp := range b.Fingerprints {
+		if fp.Matches(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// add learns a fingerprint unless an equivalent one is already known
+func (b *WildcardBaseline) add(f ResponseFingerprint) {
+	if !b.Matches(f) {
+		b.Fingerprints = append(b.Fingerprints, f)
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
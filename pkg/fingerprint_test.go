@@ -0,0 +1,48 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWildcardBaselineMatches(t *testing.T) {
+	notFound := func(path, token string) ResponseFingerprint {
+		body := "<html>\n<p>The page " + path + " was not found</p>\n<!-- " + token + " -->\n</html>"
+		return NewResponseFingerprint(404, []byte(body), path)
+	}
+	baseline := &WildcardBaseline{}
+	baseline.add(notFound("/a1b2c3d4", "1697000000"))
+	baseline.add(notFound("/e5f6a7b8", "1697000001"))
+	// The size of the catch-all page leaves room for a few bytes of difference
+	welcome := "<html>\n<p>Welcome</p>\n<!-- " + strings.Repeat("x", 200) + " -->\n</html>"
+	baseline.add(NewResponseFingerprint(200, []byte(welcome), "/c9d0e1f2"))
+	if len(baseline.Fingerprints) != 2 {
+		t.Fatalf("got %d fingerprints, want the 404 and the 200 once", len(baseline.Fingerprints))
+	}
+
+	tests := []struct {
+		desc     string
+		response ResponseFingerprint
+		want     bool
+	}{
+		{"reflected path", notFound("/admin", "1697000000"), true},
+		{"reflected base of the path", NewResponseFingerprint(404, []byte("<html>\n<p>The page login.php was not found</p>\n<!-- 1697000000 -->\n</html>"), "/app/login.php"), true},
+		{"dynamic token", notFound("/admin", "1697000099"), true},
+		{"same page with another status", NewResponseFingerprint(403, []byte(welcome), "/admin"), false},
+		{"catch-all page", NewResponseFingerprint(200, []byte(welcome), "/admin"), true},
+		{"slightly different page", NewResponseFingerprint(200, []byte(strings.Replace(welcome, "Welcome", "Welcome back", 1)), "/admin"), true},
+		{"larger page", NewResponseFingerprint(200, []byte(welcome+strings.Repeat("<p>Dashboard</p>", 20)), "/admin"), false},
+		{"more lines", NewResponseFingerprint(200, []byte(strings.Replace(welcome, "<p>", "\n\n<p>", 1)), "/admin"), false},
+		{"more words", NewResponseFingerprint(200, []byte(strings.Replace(welcome, "Welcome", "Welcome a b", 1)), "/admin"), false},
+	}
+	for _, tt := range tests {
+		if got := baseline.Matches(tt.response); got != tt.want {
+			t.Errorf("%s : got %v, want %v", tt.desc, got, tt.want)
+		}
+	}
+
+	var none *WildcardBaseline
+	if none.Matches(notFound("/admin", "1697000000")) {
+		t.Error("a missing baseline matched a response")
+	}
+}
@@ -0,0 +1,274 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Severities of audit findings
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+	SeverityInfo   = "info"
+)
+
+// Checks of the headers audit, used to search results by failing check
+const (
+	CheckCSP               = "csp"
+	CheckHSTS              = "hsts"
+	CheckFrameOptions      = "x-frame-options"
+	CheckContentTypeOpts   = "x-content-type-options"
+	CheckReferrerPolicy    = "referrer-policy"
+	CheckPermissionsPolicy = "permissions-policy"
+	CheckCookieSecure      = "cookie-secure"
+	CheckCookieHTTPOnly    = "cookie-httponly"
+	CheckCookieSameSite    = "cookie-samesite"
+	CheckServerDisclosure  = "server-disclosure"
+	CheckCORS              = "cors"
+)
+
+// severityPenalty : points removed from the score of a port for each finding
+var severityPenalty = map[string]int{
+	SeverityHigh:   25,
+	SeverityMedium: 10,
+	SeverityLow:    5,
+	SeverityInfo:   0,
+}
+
+// auditOrigin : origin sent to find out whether CORS headers reflect any origin
+const auditOrigin = "https://faryuk-audit.invalid"
+
+// hstsMinAge : minimum max-age of HSTS, 180 days
+const hstsMinAge = 15552000
+
+var versionRegexp = regexp.MustCompile(`\d+\.\d+`)
+
+// AuditFinding : a failed check of the headers audit
+type AuditFinding struct {
+	Check    string `bson:"check" json:"check"`
+	Severity string `bson:"severity" json:"severity"`
+	Message  string `bson:"message" json:"message"`
+}
+
+// HeaderAudit : security headers and cookies audit of a web port
+type HeaderAudit struct {
+	Findings []AuditFinding `bson:"findings" json:"findings"`
+	Score    int            `bson:"score" json:"score"`
+	Grade    string         `bson:"grade" json:"grade"`
+}
+
+// SecuritySummary : aggregated audit of the web ports of a host, the worst port gives the score
+type SecuritySummary struct {
+	Score   int      `bson:"score" json:"score"`
+	Grade   string   `bson:"grade" json:"grade"`
+	Failing []string `bson:"failing" json:"failing"`
+}
+
+// HeaderAuditor : struct for auditing the security headers of a web port
+type HeaderAuditor struct{}
+
+// NewHeaderAuditor : returns new HeaderAuditor
+func NewHeaderAuditor() *HeaderAuditor {
+	return &HeaderAuditor{}
+}
+
+// Run : audits the headers grabbed from a web port, a request with a foreign origin checks CORS
+func (h HeaderAuditor) Run(ctx context.Context, url string, headers map[string][]string) (*HeaderAudit, error) {
+	audit := AuditHeaders(headers, strings.HasPrefix(url, "https://"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return audit, err
+	}
+	req.Header.Set("Origin", auditOrigin)
+	resp, err := InsecureClient.Do(req)
+	if err != nil {
+		return audit, err
+	}
+	resp.Body.Close()
+
+	audit.Findings = append(audit.Findings, auditCORS(resp.Header)...)
+	audit.grade()
+	return audit, nil
+}
+
+// AuditHeaders : checks security headers, cookie flags and version disclosure of a response
+func AuditHeaders(headers map[string][]string, https bool) *HeaderAudit {
+	header := http.Header{}
+	for k, v := range headers {
+		for _, value := range v {
+			header.Add(k, value)
+		}
+	}
+
+	audit := &HeaderAudit{Findings: []AuditFinding{}}
+	add := func(check, severity, message string) {
+		audit.Findings = append(audit.Findings, AuditFinding{check, severity, message})
+	}
+
+	csp := strings.ToLower(header.Get("Content-Security-Policy"))
+	if csp == "" {
+		add(CheckCSP, SeverityMedium, "Content-Security-Policy is missing")
+	} else {
+		for _, weakness := range []string{"'unsafe-inline'", "'unsafe-eval'"} {
+			if strings.Contains(csp, weakness) {
+				add(CheckCSP, SeverityLow, "Content-Security-Policy allows "+weakness)
+			}
+		}
+		for _, directive := range strings.Split(csp, ";") {
+			fields := strings.Fields(directive)
+			if len(fields) > 1 && (fields[0] == "default-src" || fields[0] == "script-src") {
+				for _, source := range fields[1:] {
+					if source == "*" || source == "http:" || source == "https:" || source == "data:" {
+						add(CheckCSP, SeverityLow, fmt.Sprintf("Content-Security-Policy %s allows %s", fields[0], source))
+					}
+				}
+			}
+		}
+	}
+
+	if https {
+		hsts := strings.ToLower(header.Get("Strict-Transport-Security"))
+		if hsts == "" {
+			add(CheckHSTS, SeverityMedium, "Strict-Transport-Security is missing")
+		} else if age := hstsMaxAge(hsts); age < hstsMinAge {
+			add(CheckHSTS, SeverityLow, fmt.Sprintf("Strict-Transport-Security max-age %d is below %d", age, hstsMinAge))
+		}
+	}
+
+	frameOptions := strings.ToUpper(header.Get("X-Frame-Options"))
+	if frameOptions == "" && !strings.Contains(csp, "frame-ancestors") {
+		add(CheckFrameOptions, SeverityMedium, "X-Frame-Options is missing and Content-Security-Policy has no frame-ancestors")
+	} else if frameOptions != "" && frameOptions != "DENY" && frameOptions != "SAMEORIGIN" {
+		add(CheckFrameOptions, SeverityLow, "X-Frame-Options has an invalid value "+frameOptions)
+	}
+
+	if strings.ToLower(header.Get("X-Content-Type-Options")) != "nosniff" {
+		add(CheckContentTypeOpts, SeverityLow, "X-Content-Type-Options is not nosniff")
+	}
+
+	referrer := strings.ToLower(header.Get("Referrer-Policy"))
+	if referrer == "" {
+		add(CheckReferrerPolicy, SeverityLow, "Referrer-Policy is missing")
+	} else if strings.Contains(referrer, "unsafe-url") || strings.Contains(referrer, "no-referrer-when-downgrade") {
+		add(CheckReferrerPolicy, SeverityLow, "Referrer-Policy leaks full URLs with "+referrer)
+	}
+
+	if header.Get("Permissions-Policy") == "" && header.Get("Feature-Policy") == "" {
+		add(CheckPermissionsPolicy, SeverityInfo, "Permissions-Policy is missing")
+	}
+
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		if https && !cookie.Secure {
+			add(CheckCookieSecure, SeverityMedium, "Cookie "+cookie.Name+" has no Secure flag")
+		}
+		if !cookie.HttpOnly {
+			add(CheckCookieHTTPOnly, SeverityLow, "Cookie "+cookie.Name+" has no HttpOnly flag")
+		}
+		if cookie.SameSite == 0 || cookie.SameSite == http.SameSiteDefaultMode || (cookie.SameSite == http.SameSiteNoneMode && !cookie.Secure) {
+			add(CheckCookieSameSite, SeverityLow, "Cookie "+cookie.Name+" has no valid SameSite attribute")
+		}
+	}
+
+	if server := header.Get("Server"); versionRegexp.MatchString(server) {
+		add(CheckServerDisclosure, SeverityLow, "Server discloses its version "+server)
+	}
+	for _, name := range []string{"X-Powered-By", "X-AspNet-Version", "X-AspNetMvc-Version", "X-Generator"} {
+		if value := header.Get(name); value != "" {
+			add(CheckServerDisclosure, SeverityLow, fmt.Sprintf("%s discloses %s", name, value))
+		}
+	}
+
+	audit.grade()
+	return audit
+}
+
+// auditCORS : flags CORS headers of a response to a request with a foreign origin
+func auditCORS(header http.Header) []AuditFinding {
+	origin := header.Get("Access-Control-Allow-Origin")
+	credentials := strings.EqualFold(header.Get("Access-Control-Allow-Credentials"), "true")
+	switch {
+	case origin == auditOrigin && credentials:
+		return []AuditFinding{{CheckCORS, SeverityHigh, "CORS reflects any origin and allows credentials"}}
+	case origin == auditOrigin:
+		return []AuditFinding{{CheckCORS, SeverityMedium, "CORS reflects any origin"}}
+	case origin == "null":
+		return []AuditFinding{{CheckCORS, SeverityMedium, "CORS allows the null origin"}}
+	case origin == "*" && credentials:
+		return []AuditFinding{{CheckCORS, SeverityMedium, "CORS allows any origin with credentials"}}
+	case origin == "*":
+		return []AuditFinding{{CheckCORS, SeverityInfo, "CORS allows any origin"}}
+	}
+	return nil
+}
+
+func hstsMaxAge(hsts string) int {
+	for _, directive := range strings.Split(hsts, ";") {
+		directive = strings.TrimSpace(directive)
+		if strings.HasPrefix(directive, "max-age=") {
+			age, _ := strconv.Atoi(strings.Trim(strings.TrimPrefix(directive, "max-age="), `"`))
+			return age
+		}
+	}
+	return 0
+}
+
+// grade : computes the score and the grade from the findings
+func (a *HeaderAudit) grade() {
+	a.Score = 100
+	for _, f := range a.Findings {
+		a.Score -= severityPenalty[f.Severity]
+	}
+	if a.Score < 0 {
+		a.Score = 0
+	}
+	a.Grade = Grade(a.Score)
+}
+
+// Grade : returns the letter of a score from 0 to 100
+func Grade(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	}
+	return "F"
+}
+
+// Summarize : aggregates the audits of the web ports of a host, nil when none was audited
+func Summarize(audits []*HeaderAudit) *SecuritySummary {
+	var summary *SecuritySummary
+	failing := make(map[string]bool)
+	for _, a := range audits {
+		if a == nil {
+			continue
+		}
+		if summary == nil || a.Score < summary.Score {
+			summary = &SecuritySummary{Score: a.Score}
+		}
+		for _, f := range a.Findings {
+			failing[f.Check] = true
+		}
+	}
+	if summary == nil {
+		return nil
+	}
+
+	summary.Grade = Grade(summary.Score)
+	for check := range failing {
+		summary.Failing = append(summary.Failing, check)
+	}
+	sort.Strings(summary.Failing)
+	return summary
+}
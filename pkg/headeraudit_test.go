@@ -0,0 +1,169 @@
+package pkg
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// findingList : the checks and severities of findings, in order
+func findingList(findings []AuditFinding) string {
+	list := make([]string, 0, len(findings))
+	for _, f := range findings {
+		list = append(list, f.Check+"/"+f.Severity)
+	}
+	return strings.Join(list, " ")
+}
+
+func TestAuditHeaders(t *testing.T) {
+	tests := []struct {
+		desc     string
+		headers  map[string][]string
+		https    bool
+		findings string
+		score    int
+		grade    string
+	}{
+		{
+			"hardened",
+			map[string][]string{
+				"Content-Security-Policy":   {"default-src 'self'; frame-ancestors 'none'"},
+				"Strict-Transport-Security": {"max-age=31536000; includeSubDomains"},
+				"X-Content-Type-Options":    {"nosniff"},
+				"Referrer-Policy":           {"strict-origin-when-cross-origin"},
+				"Permissions-Policy":        {"camera=()"},
+				"Set-Cookie":                {"id=1; Secure; HttpOnly; SameSite=Lax"},
+				"Server":                    {"nginx"},
+			},
+			true, "", 100, "A",
+		},
+		{
+			"nothing over http",
+			map[string][]string{},
+			false,
+			"csp/medium x-frame-options/medium x-content-type-options/low referrer-policy/low permissions-policy/info",
+			70, "C",
+		},
+		{
+			"nothing over https",
+			map[string][]string{},
+			true,
+			"csp/medium hsts/medium x-frame-options/medium x-content-type-options/low referrer-policy/low permissions-policy/info",
+			60, "D",
+		},
+		{
+			"weak",
+			map[string][]string{
+				"content-security-policy":   {"default-src * 'unsafe-inline'"},
+				"Strict-Transport-Security": {`max-age="600"`},
+				"X-Frame-Options":           {"ALLOW-FROM https://example.com"},
+				"X-Content-Type-Options":    {"nosniff"},
+				"Referrer-Policy":           {"unsafe-url"},
+				"Feature-Policy":            {"camera 'none'"},
+				"Set-Cookie":                {"id=1"},
+				"Server":                    {"Apache/2.4.1"},
+				"X-Powered-By":              {"PHP/8.1"},
+			},
+			true,
+			"csp/low csp/low hsts/low x-frame-options/low referrer-policy/low cookie-secure/medium cookie-httponly/low cookie-samesite/low server-disclosure/low server-disclosure/low",
+			45, "F",
+		},
+		{
+			"cookies over http",
+			map[string][]string{
+				"Content-Security-Policy": {"frame-ancestors 'self'"},
+				"X-Content-Type-Options":  {"nosniff"},
+				"Referrer-Policy":         {"no-referrer"},
+				"Permissions-Policy":      {"camera=()"},
+				"Set-Cookie":              {"a=1; HttpOnly; SameSite=None", "b=2; HttpOnly; SameSite=Strict"},
+			},
+			false, "cookie-samesite/low", 95, "A",
+		},
+	}
+	for _, tt := range tests {
+		audit := AuditHeaders(tt.headers, tt.https)
+		if got := findingList(audit.Findings); got != tt.findings {
+			t.Errorf("%s : got findings %q, want %q", tt.desc, got, tt.findings)
+		}
+		if audit.Score != tt.score || audit.Grade != tt.grade {
+			t.Errorf("%s : got score %d grade %s, want %d %s", tt.desc, audit.Score, audit.Grade, tt.score, tt.grade)
+		}
+	}
+}
+
+func TestAuditCORS(t *testing.T) {
+	tests := []struct {
+		origin      string
+		credentials string
+		findings    string
+	}{
+		{auditOrigin, "true", "cors/high"},
+		{auditOrigin, "", "cors/medium"},
+		{"null", "", "cors/medium"},
+		{"*", "TRUE", "cors/medium"},
+		{"*", "", "cors/info"},
+		{"https://example.com", "true", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.origin != "" {
+			header.Set("Access-Control-Allow-Origin", tt.origin)
+		}
+		if tt.credentials != "" {
+			header.Set("Access-Control-Allow-Credentials", tt.credentials)
+		}
+		if got := findingList(auditCORS(header)); got != tt.findings {
+			t.Errorf("origin %q with credentials %q : got %q, want %q", tt.origin, tt.credentials, got, tt.findings)
+		}
+	}
+}
+
+func TestHeaderAuditGrade(t *testing.T) {
+	tests := []struct {
+		severities []string
+		score      int
+		grade      string
+	}{
+		{nil, 100, "A"},
+		{[]string{SeverityInfo, SeverityLow, SeverityLow}, 90, "A"},
+		{[]string{SeverityMedium, SeverityLow, SeverityLow}, 80, "B"},
+		{[]string{SeverityHigh, SeverityLow}, 70, "C"},
+		{[]string{SeverityHigh, SeverityMedium, SeverityLow}, 60, "D"},
+		{[]string{SeverityHigh, SeverityHigh, SeverityLow}, 45, "F"},
+		{[]string{SeverityHigh, SeverityHigh, SeverityHigh, SeverityHigh, SeverityHigh}, 0, "F"},
+	}
+	for _, tt := range tests {
+		audit := &HeaderAudit{}
+		for _, severity := range tt.severities {
+			audit.Findings = append(audit.Findings, AuditFinding{Check: CheckCORS, Severity: severity})
+		}
+		audit.grade()
+		if audit.Score != tt.score || audit.Grade != tt.grade {
+			t.Errorf("%v : got score %d grade %s, want %d %s", tt.severities, audit.Score, audit.Grade, tt.score, tt.grade)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	if summary := Summarize([]*HeaderAudit{nil, nil}); summary != nil {
+		t.Errorf("got %+v without audits, want nil", *summary)
+	}
+
+	audits := []*HeaderAudit{
+		{Score: 80, Findings: []AuditFinding{{Check: CheckHSTS}, {Check: CheckCSP}}},
+		nil,
+		{Score: 55, Findings: []AuditFinding{{Check: CheckCORS}, {Check: CheckCSP}}},
+		{Score: 100, Findings: []AuditFinding{}},
+	}
+	summary := Summarize(audits)
+	if summary == nil {
+		t.Fatal("got no summary")
+	}
+	if summary.Score != 55 || summary.Grade != "F" {
+		t.Errorf("got score %d grade %s, want the worst port 55 F", summary.Score, summary.Grade)
+	}
+	if got := strings.Join(summary.Failing, " "); got != "cors csp hsts" {
+		t.Errorf("got failing checks %q, want cors csp hsts", got)
+	}
+}
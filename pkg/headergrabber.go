@@ -1,10 +1,19 @@
 package pkg
 
 import (
+	"context"
 	"crypto/tls"
 	"net/http"
 )
 
+// InsecureClient : HTTP client accepting any certificate, certificates are analyzed by TLSGrabber
+var InsecureClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	},
+}
+
 // HeaderGrabber : struct for grabbing headers
 type HeaderGrabber struct{}
 
@@ -14,13 +23,17 @@ func NewHeaderGrabber() *HeaderGrabber {
 }
 
 // Run : gets HTTP headers
-func (h HeaderGrabber) Run(url string) (map[string][]string, error) {
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	resp, err := http.Head(url)
+func (h HeaderGrabber) Run(ctx context.Context, url string) (map[string][]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := InsecureClient.Do(req)
 
 	if err != nil {
 		return nil, err
 	}
+	resp.Body.Close()
 
 	headers := make(map[string][]string)
 	for k, v := range resp.Header {
@@ -0,0 +1,110 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// permutationAffixes : environments and roles commonly prefixed or suffixed to subdomains
+var permutationAffixes = []string{
+	"dev", "develop", "staging", "stage", "stg", "test", "qa", "uat", "preprod", "prod",
+	"int", "internal", "ext", "admin", "api", "app", "beta", "demo", "old", "new",
+	"legacy", "backup", "v1", "v2", "m", "mobile", "cdn", "static", "portal", "sso",
+}
+
+var trailingDigitsRegexp = regexp.MustCompile(`^(.*?)(\d+)$`)
+
+// maxLabelLength : longest label of a domain name
+const maxLabelLength = 63
+
+// Permutations : returns at most max names derived from discovered subdomains, names are relative to the domain and
+// known names are left out. Every kind of alteration is applied to all the names before the next one so that the
+// cap keeps the most likely candidates
+func Permutations(known []string, max int) []string {
+	seen := make(map[string]bool)
+	for _, name := range known {
+		seen[strings.ToLower(name)] = true
+	}
+
+	res := make([]string, 0)
+	// The rest of a name without subdomain levels is empty
+	add := func(labels ...string) {
+		parts := make([]string, 0, len(labels))
+		for _, label := range labels {
+			if label == "" {
+				continue
+			}
+			if len(label) > maxLabelLength || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+				return
+			}
+			parts = append(parts, label)
+		}
+		name := strings.Join(parts, ".")
+		if len(res) < max && !seen[name] {
+			seen[name] = true
+			res = append(res, name)
+		}
+	}
+
+	passes := []func(label, rest string){
+		// Number increments, api2 gives api1 and api3, api gives api1 and api2
+		func(label, rest string) {
+			m := trailingDigitsRegexp.FindStringSubmatch(label)
+			if m == nil {
+				add(label+"1", rest)
+				add(label+"2", rest)
+				return
+			}
+			n, err := strconv.Atoi(m[2])
+			if err != nil {
+				return
+			}
+			format := "%0" + strconv.Itoa(len(m[2])) + "d"
+			if n > 0 {
+				add(m[1]+fmt.Sprintf(format, n-1), rest)
+			}
+			add(m[1]+fmt.Sprintf(format, n+1), rest)
+		},
+		func(label, rest string) {
+			for _, affix := range permutationAffixes {
+				if affix != label {
+					add(affix+"-"+label, rest)
+					add(label+"-"+affix, rest)
+				}
+			}
+		},
+		func(label, rest string) {
+			for _, affix := range permutationAffixes {
+				if affix != label {
+					add(label, affix, rest)
+					add(affix, label, rest)
+				}
+			}
+		},
+		func(label, rest string) {
+			for _, affix := range permutationAffixes {
+				if affix != label {
+					add(affix+label, rest)
+					add(label+affix, rest)
+				}
+			}
+		},
+	}
+
+	for _, pass := range passes {
+		for _, name := range known {
+			if len(res) >= max {
+				return res
+			}
+			name = strings.ToLower(name)
+			label, rest := name, ""
+			if idx := strings.Index(name, "."); idx >= 0 {
+				label, rest = name[:idx], name[idx+1:]
+			}
+			pass(label, rest)
+		}
+	}
+	return res
+}
@@ -0,0 +1,54 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPermutations(t *testing.T) {
+	long := strings.Repeat("a", maxLabelLength)
+	tests := []struct {
+		known []string
+		max   int
+		want  []string
+	}{
+		{[]string{"api2", "www"}, 0, []string{}},
+		{[]string{"api2", "www"}, 4, []string{"api1", "api3", "www1", "www2"}},
+		// Increments of all the names come before the affixes
+		{[]string{"api2", "www"}, 6, []string{"api1", "api3", "www1", "www2", "dev-api2", "api2-dev"}},
+		{[]string{"node09", "web0"}, 3, []string{"node08", "node10", "web1"}},
+		{[]string{"api1", "API2"}, 3, []string{"api0", "api3", "dev-api1"}},
+		{[]string{"Mail.EU"}, 4, []string{"mail1.eu", "mail2.eu", "dev-mail.eu", "mail-dev.eu"}},
+		// Longer labels are left out
+		{[]string{long}, 2, []string{long + ".dev", "dev." + long}},
+		{[]string{"dev"}, 4, []string{"dev1", "dev2", "develop-dev", "dev-develop"}},
+	}
+	for _, tt := range tests {
+		got := Permutations(tt.known, tt.max)
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("Permutations(%v, %d) = %v, want %v", tt.known, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestPermutationsAll(t *testing.T) {
+	known := []string{"api", "mail.eu"}
+	got := Permutations(known, 100000)
+	// 2 increments, then 2 names per affix in 3 passes, api is an affix itself
+	if want := 2 + 6*(len(permutationAffixes)-1) + 2 + 6*len(permutationAffixes); len(got) != want {
+		t.Errorf("got %d permutations, want %d", len(got), want)
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range got {
+		if seen[name] || name == "api" || name == "mail.eu" {
+			t.Errorf("%s is given twice or is known", name)
+		}
+		seen[name] = true
+	}
+	for _, name := range []string{"api.dev", "dev.api", "devapi", "apidev", "mail.dev.eu", "dev.mail.eu", "mail-sso.eu"} {
+		if !seen[name] {
+			t.Errorf("%s is missing", name)
+		}
+	}
+}
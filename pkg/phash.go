@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+// phashSize : side of the grayscale image the DCT is computed on
+const phashSize = 32
+
+// PerceptualHash : returns the 64 bits pHash of an image as hex, similar images have hashes with a small Hamming distance
+func PerceptualHash(data []byte) (string, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	// Grayscale image scaled down to phashSize x phashSize, each pixel averages the box it covers
+	bounds := src.Bounds()
+	if bounds.Dx() < phashSize || bounds.Dy() < phashSize {
+		return "", fmt.Errorf("image is too small to be hashed")
+	}
+	var pixels [phashSize][phashSize]float64
+	for y := 0; y < phashSize; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/phashSize
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/phashSize
+		for x := 0; x < phashSize; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/phashSize
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/phashSize
+			sum, n := 0.0, 0.0
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					r, g, b, _ := src.At(sx, sy).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+					n++
+				}
+			}
+			pixels[y][x] = sum / n
+		}
+	}
+
+	// The lowest 8x8 frequencies of the DCT, without the constant one, are compared to their median
+	var coefs []float64
+	for v := 0; v < 8; v++ {
+		for u := 0; u < 8; u++ {
+			if u == 0 && v == 0 {
+				continue
+			}
+			coefs = append(coefs, dct(&pixels, u, v))
+		}
+	}
+	sorted := append([]float64{}, coefs...)
+	sort.Float64s(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	var hash uint64
+	for idx, c := range coefs {
+		if c > median {
+			hash |= 1 << uint(idx)
+		}
+	}
+	return fmt.Sprintf("%016x", hash), nil
+}
+
+func dct(pixels *[phashSize][phashSize]float64, u, v int) float64 {
+	sum := 0.0
+	for y := 0; y < phashSize; y++ {
+		for x := 0; x < phashSize; x++ {
+			sum += pixels[y][x] *
+				math.Cos(float64(2*x+1)*float64(u)*math.Pi/(2*phashSize)) *
+				math.Cos(float64(2*y+1)*float64(v)*math.Pi/(2*phashSize))
+		}
+	}
+	return sum
+}
+
+// HashDistance : returns the Hamming distance between two perceptual hashes
+func HashDistance(a, b string) (int, error) {
+	x, err := strconv.ParseUint(a, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+	y, err := strconv.ParseUint(b, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+	return bits.OnesCount64(x ^ y), nil
+}
+
+// ClusterHashes : groups hashes within maxDistance of the first hash of their group, it returns groups of indexes,
+// the largest first, invalid hashes are left out
+func ClusterHashes(hashes []string, maxDistance int) [][]int {
+	var leaders []string
+	var clusters [][]int
+	for idx, hash := range hashes {
+		found := false
+		for c, leader := range leaders {
+			if d, err := HashDistance(leader, hash); err == nil && d <= maxDistance {
+				clusters[c] = append(clusters[c], idx)
+				found = true
+				break
+			}
+		}
+		if _, err := strconv.ParseUint(hash, 16, 64); !found && err == nil {
+			leaders = append(leaders, hash)
+			clusters = append(clusters, []int{idx})
+		}
+	}
+	sort.SliceStable(clusters, func(i, j int) bool { return len(clusters[i]) > len(clusters[j]) })
+	return clusters
+}
@@ -0,0 +1,138 @@
+package pkg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// encodeImage : returns the PNG of a grayscale image of the given size drawn by shade
+func encodeImage(t *testing.T, width, height int, shade func(x, y float64) uint8) []byte {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetGray(x, y, color.Gray{shade(float64(x)/float64(width), float64(y)/float64(height))})
+		}
+	}
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// loginPage : a dark header and a light form in the middle of a white page
+func loginPage(x, y float64) uint8 {
+	switch {
+	case y < 0.15:
+		return 40
+	case x > 0.3 && x < 0.7 && y > 0.35 && y < 0.65:
+		return 180
+	}
+	return 250
+}
+
+func TestPerceptualHash(t *testing.T) {
+	hash := func(data []byte) string {
+		h, err := PerceptualHash(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	reference := hash(encodeImage(t, 256, 192, loginPage))
+
+	tests := []struct {
+		desc    string
+		data    []byte
+		minDist int
+		maxDist int
+	}{
+		{"same page", encodeImage(t, 256, 192, loginPage), 0, 0},
+		{"larger screenshot", encodeImage(t, 1024, 768, loginPage), 0, 4},
+		{"darker page", encodeImage(t, 256, 192, func(x, y float64) uint8 { return loginPage(x, y) - 30 }), 0, 4},
+		{"inverted page", encodeImage(t, 256, 192, func(x, y float64) uint8 { return 255 - loginPage(x, y) }), 40, 64},
+		{"other page", encodeImage(t, 256, 192, func(x, y float64) uint8 {
+			if x < 0.25 {
+				return 30
+			}
+			return uint8(255 * y)
+		}), 15, 64},
+	}
+	for _, tt := range tests {
+		d, err := HashDistance(reference, hash(tt.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d < tt.minDist || d > tt.maxDist {
+			t.Errorf("%s : got distance %d, want between %d and %d", tt.desc, d, tt.minDist, tt.maxDist)
+		}
+	}
+
+	_, err := PerceptualHash(encodeImage(t, 16, 64, loginPage))
+	if err == nil {
+		t.Error("got a hash of an image smaller than the DCT")
+	}
+	_, err = PerceptualHash([]byte("not an image"))
+	if err == nil {
+		t.Error("got a hash of invalid data")
+	}
+}
+
+func TestHashDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"0000000000000000", "0000000000000000", 0},
+		{"0000000000000000", "ffffffffffffffff", 64},
+		{"00000000000000f0", "0000000000000001", 5},
+	}
+	for _, tt := range tests {
+		got, err := HashDistance(tt.a, tt.b)
+		if err != nil || got != tt.want {
+			t.Errorf("HashDistance(%s, %s) = %d, %v, want %d", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+	_, err := HashDistance("0000000000000000", "not a hash")
+	if err == nil {
+		t.Error("got a distance to an invalid hash")
+	}
+}
+
+func TestClusterHashes(t *testing.T) {
+	hashes := []string{
+		"0000000000000000",
+		"ffffffffffffffff",
+		"0000000000000003",
+		"",
+		"fffffffffffffff0",
+		"00000000000000ff",
+		"0000000000000001",
+		"7fffffffffffffff",
+	}
+	want := [][]int{{0, 2, 6}, {1, 4, 7}, {5}}
+	got := ClusterHashes(hashes, 4)
+	if len(got) != len(want) {
+		t.Fatalf("got clusters %v, want %v", got, want)
+	}
+	for c := range want {
+		if len(got[c]) != len(want[c]) {
+			t.Errorf("got cluster %v, want %v", got[c], want[c])
+			continue
+		}
+		for idx := range want[c] {
+			if got[c][idx] != want[c][idx] {
+				t.Errorf("got cluster %v, want %v", got[c], want[c])
+				break
+			}
+		}
+	}
+
+	if got := ClusterHashes(hashes, 0); len(got) != 7 || len(got[0]) != 1 {
+		t.Errorf("got clusters %v with a distance of 0, want one per valid hash", got)
+	}
+}
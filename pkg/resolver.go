@@ -1,20 +1,22 @@
 package pkg
 
 import (
-	"net"
+	"context"
 )
 
 // Resolver : struct for resolving hostnames
-type Resolver struct{}
+type Resolver struct {
+	pool *ResolverPool
+}
 
-// NewResolver : returns new Resolver
+// NewResolver : returns new Resolver using the default resolver pool
 func NewResolver() *Resolver {
-	return &Resolver{}
+	return &Resolver{DefaultResolverPool()}
 }
 
 // Resolve : resolves a hostname and returns a slice of ips
-func (r Resolver) Resolve(hostname string) []string {
-	ips, err := net.LookupHost(hostname)
+func (r Resolver) Resolve(ctx context.Context, hostname string) []string {
+	ips, err := r.pool.LookupHost(ctx, hostname)
 	if err != nil {
 		return make([]string, 0)
 	}
@@ -22,10 +24,10 @@ func (r Resolver) Resolve(hostname string) []string {
 }
 
 // Run : resolves a list of hosts by calling Resolve repeatedly
-func (r Resolver) Run(hosts []string) map[string][]string {
+func (r Resolver) Run(ctx context.Context, hosts []string) map[string][]string {
 	ret := make(map[string][]string)
 	for i := range hosts {
-		result := r.Resolve(hosts[i])
+		result := r.Resolve(ctx, hosts[i])
 		ret[hosts[i]] = result
 	}
 	return ret
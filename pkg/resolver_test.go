@@ -1,6 +1,7 @@
 package pkg
 
 import (
+	"context"
 	"log"
 	"testing"
 )
@@ -8,6 +9,6 @@ import (
 func TestNewResolver(t *testing.T) {
 	p := NewResolver()
 	arg := []string{"www.sncf.com", "www.google.com"}
-	res := p.Run(arg)
+	res := p.Run(context.Background(), arg)
 	log.Println(res)
 }
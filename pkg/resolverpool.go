@@ -0,0 +1,344 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// maxResolverFailures : consecutive timeouts or errors after which a resolver is excluded
+	maxResolverFailures = 3
+	// resolverCooldown : time after which a resolver excluded for failures is used again
+	resolverCooldown = 5 * time.Minute
+	// maxLookupAttempts : resolvers tried by a lookup before giving up
+	maxLookupAttempts = 3
+	// confirmEvery : one positive answer of a resolver out of confirmEvery is checked with the trusted resolver
+	confirmEvery = 10
+	// maxResolverMismatches : consecutive answers denied by the trusted resolver after which a resolver is excluded
+	maxResolverMismatches = 3
+)
+
+// systemResolverName : address given to the resolver of the system in the stats
+const systemResolverName = "system"
+
+// ResolverStats : counters of a resolver of a pool
+type ResolverStats struct {
+	Address  string `json:"address"`
+	Queries  int    `json:"queries"`
+	Answers  int    `json:"answers"`
+	NotFound int    `json:"notFound"`
+	Errors   int    `json:"errors"`
+	Poisoned int    `json:"poisoned"`
+	// AverageLatency is in milliseconds
+	AverageLatency int64  `json:"averageLatency"`
+	Excluded       bool   `json:"excluded"`
+	Reason         string `json:"reason"`
+}
+
+type poolResolver struct {
+	address  string
+	resolver *net.Resolver
+	// client : raw client for the records net.Resolver does not give, clientErr when it cannot be created
+	client    *DNSClient
+	clientErr error
+	throttle  *Throttle
+	stats     ResolverStats
+	latency   time.Duration
+	// failures : consecutive timeouts or errors
+	failures int
+	// mismatches : consecutive checked answers denied by the trusted resolver
+	mismatches    int
+	excludedUntil time.Time
+}
+
+// ResolverPool : resolvers used in turn by lookups, the ones timing out or returning answers the trusted resolver
+// does not confirm are excluded for a while. The pool falls back on them when all of them are excluded
+type ResolverPool struct {
+	mu        sync.Mutex
+	resolvers []*poolResolver
+	next      int
+	trusted   *poolResolver
+	timeout   time.Duration
+}
+
+var (
+	defaultResolverPoolMu sync.Mutex
+	defaultResolverPool   *ResolverPool
+)
+
+// newPoolResolver : returns a resolver sending at most rate queries per second to address, the system one when
+// address is empty
+func newPoolResolver(address string, rate int, timeout time.Duration) *poolResolver {
+	r := &poolResolver{address: systemResolverName, resolver: net.DefaultResolver, throttle: NewThrottle(rate)}
+	if address != "" {
+		r.address = resolverAddress(address)
+		r.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial:     newCustomDialer(address),
+		}
+	}
+	r.client, r.clientErr = NewDNSClient(address, timeout)
+	return r
+}
+
+// NewResolverPool : returns a pool of resolvers, the system one when addresses is empty. A sample of the positive
+// answers is checked with the trusted resolver, the system one when trusted is empty. rate is the maximum number of
+// queries per second sent to each resolver, the trusted one included, 0 means unlimited
+func NewResolverPool(addresses []string, trusted string, timeout time.Duration, rate int) *ResolverPool {
+	p := &ResolverPool{timeout: timeout}
+	seen := make(map[string]bool)
+	for _, address := range addresses {
+		r := newPoolResolver(address, rate, timeout)
+		if address != "" && !seen[r.address] {
+			seen[r.address] = true
+			p.resolvers = append(p.resolvers, r)
+		}
+	}
+	if len(p.resolvers) == 0 {
+		p.resolvers = append(p.resolvers, newPoolResolver("", rate, timeout))
+	}
+	p.trusted = newPoolResolver(trusted, rate, timeout)
+	// The trusted resolver shares the limit of the resolver of the pool at the same address
+	for _, r := range p.resolvers {
+		if r.address == p.trusted.address {
+			p.trusted = r
+		}
+	}
+	for _, r := range p.resolvers {
+		r.stats.Address = r.address
+	}
+	return p
+}
+
+// SetResolverPool : replaces the pool used by lookups which are not given their own resolvers
+func SetResolverPool(p *ResolverPool) {
+	defaultResolverPoolMu.Lock()
+	defer defaultResolverPoolMu.Unlock()
+	defaultResolverPool = p
+}
+
+// DefaultResolverPool : returns the pool used by lookups which are not given their own resolvers, the system resolver
+// unless SetResolverPool was called
+func DefaultResolverPool() *ResolverPool {
+	defaultResolverPoolMu.Lock()
+	defer defaultResolverPoolMu.Unlock()
+	if defaultResolverPool == nil {
+		defaultResolverPool = NewResolverPool(nil, "", 2*time.Second, 0)
+	}
+	return defaultResolverPool
+}
+
+// Addresses : returns the addresses of the resolvers of the pool
+func (p *ResolverPool) Addresses() []string {
+	res := make([]string, len(p.resolvers))
+	for idx, r := range p.resolvers {
+		res[idx] = r.address
+	}
+	return res
+}
+
+// Stats : returns the counters of the resolvers of the pool
+func (p *ResolverPool) Stats() []ResolverStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	res := make([]ResolverStats, len(p.resolvers))
+	for idx, r := range p.resolvers {
+		res[idx] = r.stats
+		res[idx].Excluded = now.Before(r.excludedUntil)
+		if r.stats.Queries > 0 {
+			res[idx].AverageLatency = (r.latency / time.Duration(r.stats.Queries)).Milliseconds()
+		}
+	}
+	return res
+}
+
+// Available : returns the number of resolvers of the pool which are not excluded
+func (p *ResolverPool) Available() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	available := 0
+	for _, r := range p.resolvers {
+		if !now.Before(r.excludedUntil) {
+			available++
+		}
+	}
+	return available
+}
+
+// pick : returns the next resolver which is not excluded. When every resolver is excluded, the one whose exclusion
+// ends first is used rather than failing every lookup until then
+func (p *ResolverPool) pick() *poolResolver {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var fallback *poolResolver
+	for range p.resolvers {
+		r := p.resolvers[p.next]
+		p.next = (p.next + 1) % len(p.resolvers)
+		if !now.Before(r.excludedUntil) {
+			return r
+		}
+		if fallback == nil || r.excludedUntil.Before(fallback.excludedUntil) {
+			fallback = r
+		}
+	}
+	return fallback
+}
+
+// record : updates the counters of a resolver with the outcome of a lookup
+func (p *ResolverPool) record(r *poolResolver, err error, elapsed time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r.stats.Queries++
+	r.latency += elapsed
+
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil:
+		r.stats.Answers++
+		r.failures = 0
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		r.stats.NotFound++
+		r.failures = 0
+	default:
+		r.stats.Errors++
+		r.failures++
+		if r.failures >= maxResolverFailures {
+			r.failures = 0
+			r.excludedUntil = time.Now().Add(resolverCooldown)
+			r.stats.Reason = err.Error()
+		}
+	}
+}
+
+// shouldConfirm : tells whether the next positive answer of a resolver is part of the sample checked
+func (p *ResolverPool) shouldConfirm(r *poolResolver) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return r.address != p.trusted.address && r.stats.Answers%confirmEvery == 1
+}
+
+// poison : counts an answer the trusted resolver denies, the resolver is excluded for a while after
+// maxResolverMismatches in a row
+func (p *ResolverPool) poison(r *poolResolver, host string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r.stats.Poisoned++
+	r.mismatches++
+	if r.mismatches >= maxResolverMismatches {
+		r.mismatches = 0
+		r.excludedUntil = time.Now().Add(resolverCooldown)
+		r.stats.Reason = "answered for " + host + " which does not exist"
+	}
+}
+
+// confirmed : resets the count of denied answers of a resolver
+func (p *ResolverPool) confirmed(r *poolResolver) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r.mismatches = 0
+}
+
+// LookupHost : resolves a hostname with the next resolver of the pool, the next resolvers are tried when one fails
+func (p *ResolverPool) LookupHost(ctx context.Context, host string) ([]string, error) {
+	var err error
+	for attempt := 0; attempt < maxLookupAttempts; attempt++ {
+		r := p.pick()
+		err = r.throttle.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var ips []string
+		lookupCtx, cancel := context.WithTimeout(ctx, p.timeout)
+		start := time.Now()
+		ips, err = r.resolver.LookupHost(lookupCtx, host)
+		elapsed := time.Since(start)
+		cancel()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		p.record(r, err, elapsed)
+
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, err
+		}
+		if err == nil && p.shouldConfirm(r) {
+			return p.confirm(ctx, r, host, ips)
+		}
+		if err == nil {
+			return ips, nil
+		}
+	}
+	return nil, err
+}
+
+// Query : returns the records of the answer of the next resolver of the pool to a query, a name which does not exist
+// has no record. The next resolvers are tried when one fails
+func (p *ResolverPool) Query(ctx context.Context, name string, qtype dnsmessage.Type) ([]DNSRecord, error) {
+	var err error
+	for attempt := 0; attempt < maxLookupAttempts; attempt++ {
+		r := p.pick()
+		if r.clientErr != nil {
+			return nil, r.clientErr
+		}
+		err = r.throttle.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var rcode dnsmessage.RCode
+		var records []DNSRecord
+		start := time.Now()
+		rcode, records, err = r.client.query(ctx, name, qtype)
+		elapsed := time.Since(start)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		outcome := err
+		if err == nil && rcode == dnsmessage.RCodeNameError {
+			outcome = &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+		}
+		p.record(r, outcome, elapsed)
+		if err == nil {
+			return records, nil
+		}
+	}
+	return nil, err
+}
+
+// Lookup : returns the records of every type of RecordTypes for a name, the records of common SRV services under the
+// name are added when withServices is set. The queries are shared by the resolvers of the pool
+func (p *ResolverPool) Lookup(ctx context.Context, name string, withServices bool) ([]DNSRecord, error) {
+	return lookupRecords(ctx, p.Query, name, withServices)
+}
+
+// confirm : checks that a name a resolver answered for exists according to the trusted resolver
+func (p *ResolverPool) confirm(ctx context.Context, r *poolResolver, host string, ips []string) ([]string, error) {
+	err := p.trusted.throttle.Wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	_, err = p.trusted.resolver.LookupHost(ctx, host)
+
+	// A trusted resolver which cannot be reached confirms nothing
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		p.poison(r, host)
+		return nil, err
+	}
+	if err == nil {
+		p.confirmed(r)
+	}
+	return ips, nil
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestResolverPoolSingleResolver(t *testing.T) {
+	server := recordServer(t, map[string][4]byte{"up.example.test": {10, 0, 0, 1}})
+	pool := NewResolverPool([]string{server}, server, 2*time.Second, 0)
+
+	// Transient errors exclude the only resolver of the pool
+	r := pool.resolvers[0]
+	for i := 0; i < maxResolverFailures; i++ {
+		pool.record(r, errors.New("i/o timeout"), time.Millisecond)
+	}
+	if stats := pool.Stats(); !stats[0].Excluded {
+		t.Fatalf("got stats %+v, want the resolver excluded", stats[0])
+	}
+
+	ips, err := pool.LookupHost(context.Background(), "up.example.test")
+	if err != nil || len(ips) != 1 || ips[0] != "10.0.0.1" {
+		t.Errorf("got %v, %v with the only resolver excluded, want 10.0.0.1", ips, err)
+	}
+}
+
+func TestResolverPoolPick(t *testing.T) {
+	pool := NewResolverPool([]string{"192.0.2.1", "192.0.2.2", "192.0.2.3"}, "", time.Second, 0)
+	first, second, third := pool.resolvers[0], pool.resolvers[1], pool.resolvers[2]
+	now := time.Now()
+	second.excludedUntil = now.Add(time.Minute)
+
+	for i, want := range []*poolResolver{first, third, first} {
+		if got := pool.pick(); got != want {
+			t.Errorf("pick %d gave %s, want %s", i, got.address, want.address)
+		}
+	}
+
+	// The resolver excluded first is used again first
+	first.excludedUntil = now.Add(3 * time.Minute)
+	third.excludedUntil = now.Add(2 * time.Minute)
+	for i := 0; i < 3; i++ {
+		if got := pool.pick(); got != second {
+			t.Errorf("pick %d gave %s with every resolver excluded, want %s", i, got.address, second.address)
+		}
+	}
+}
+
+func TestResolverPoolQuery(t *testing.T) {
+	server := recordServer(t, map[string][4]byte{"up.example.test": {10, 0, 0, 1}})
+	pool := NewResolverPool([]string{server}, server, 2*time.Second, 0)
+
+	records, err := pool.Query(context.Background(), "up.example.test", dnsmessage.TypeA)
+	if err != nil || len(records) != 1 || records[0].Value != "10.0.0.1" {
+		t.Errorf("got %v, %v, want the A record 10.0.0.1", records, err)
+	}
+	records, err = pool.Query(context.Background(), "gone.example.test", dnsmessage.TypeCNAME)
+	if err != nil || len(records) != 0 {
+		t.Errorf("got %v, %v for a name which does not exist, want no record", records, err)
+	}
+	if stats := pool.Stats()[0]; stats.Queries != 2 || stats.Answers != 1 || stats.NotFound != 1 {
+		t.Errorf("got stats %+v, want 2 queries, 1 answer and 1 name not found", stats)
+	}
+
+	records, err = pool.Lookup(context.Background(), "up.example.test", false)
+	if err != nil || len(records) != 1 || records[0].Type != "A" {
+		t.Errorf("got %v, %v, want only the A record", records, err)
+	}
+	if stats := pool.Stats()[0]; stats.Queries != 2+len(RecordTypes) {
+		t.Errorf("got %d queries, want one more per record type", stats.Queries)
+	}
+}
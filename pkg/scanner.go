@@ -1,8 +1,9 @@
 package pkg
 
 import (
-	"fmt"
+	"context"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -20,12 +21,13 @@ func NewPortScanner(host string, timeout time.Duration, threads int) *PortScanne
 }
 
 // IsOpen : checks if a port is open
-func (h PortScanner) IsOpen(port int) bool {
-	tcpAddr, err := net.ResolveTCPAddr("tcp4", h.hostPort(port))
+func (h PortScanner) IsOpen(ctx context.Context, port int) bool {
+	tcpAddr, err := net.ResolveTCPAddr("tcp", h.hostPort(port))
 	if err != nil {
 		return false
 	}
-	conn, err := net.DialTimeout("tcp", tcpAddr.String(), h.timeout)
+	d := net.Dialer{Timeout: h.timeout}
+	conn, err := d.DialContext(ctx, "tcp", tcpAddr.String())
 	if err != nil {
 		return false
 	}
@@ -35,18 +37,23 @@ func (h PortScanner) IsOpen(port int) bool {
 }
 
 func (h PortScanner) hostPort(port int) string {
-	return fmt.Sprintf("%s:%d", h.host, port)
+	return net.JoinHostPort(h.host, strconv.Itoa(port))
 }
 
-// Run : returns a list of open ports from a given list
-func (h PortScanner) Run(ports []int) []int {
+// Run : returns a list of open ports from a given list, it stops early when ctx is done
+func (h PortScanner) Run(ctx context.Context, ports []int) []int {
 	rv := []int{}
 	l := sync.Mutex{}
 	sem := make(chan bool, h.threads)
+loop:
 	for _, port := range ports {
-		sem <- true
+		select {
+		case <-ctx.Done():
+			break loop
+		case sem <- true:
+		}
 		go func(port int) {
-			if h.IsOpen(port) {
+			if h.IsOpen(ctx, port) {
 				l.Lock()
 				rv = append(rv, port)
 				l.Unlock()
@@ -2,64 +2,141 @@ package pkg
 
 import (
 	"context"
-	"encoding/base64"
+	"math"
+	"sync"
 	"time"
 
+	"github.com/chromedp/cdproto/emulation"
 	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
 )
 
-// ScreenerResult : base64 of the screenshot
+// ScreenerResult : screenshot of a page, only its ID in the blob store is kept in results
 type ScreenerResult struct {
-	Path string `bson:"path" json:"path"`
+	ID string `bson:"id" json:"id"`
+	// Hash is the perceptual hash of the screenshot
+	Hash string `bson:"hash" json:"hash"`
+	// Path holds the base64 of screenshots taken before the blob store, until they are migrated
+	Path  string `bson:"path" json:"path"`
+	Image []byte `bson:"-" json:"-"`
+}
+
+// ScreenerOptions : options of the screenshots
+type ScreenerOptions struct {
+	// Timeout of a screenshot, loading included
+	Timeout time.Duration
+	// IdleWait is the longest wait for the network to be idle once the page is loaded
+	IdleWait time.Duration
+	Width    int
+	Height   int
+	FullPage bool
 }
 
 // Screener : struct to take screenshots
-type Screener struct{}
+type Screener struct {
+	pool *BrowserPool
+	opts ScreenerOptions
+}
 
 // NewScreenerResult : returns a new ScreenerResult struct
-func NewScreenerResult(path string) *ScreenerResult {
-	return &ScreenerResult{path}
+func NewScreenerResult(image []byte) *ScreenerResult {
+	return &ScreenerResult{Image: image}
 }
 
-// NewScreener : returns a new Screener struct
-func NewScreener() *Screener {
-	return &Screener{}
+// NewScreener : returns a new Screener struct taking screenshots in tabs of pool
+func NewScreener(pool *BrowserPool, opts ScreenerOptions) *Screener {
+	return &Screener{pool, opts}
 }
 
-// Run : takes a screenshot using chrome headless
-func (s Screener) Run(url string) (ScreenerResult, error) {
+// Run : takes a screenshot in a tab of the pool, the tab is closed when ctx is done
+func (s Screener) Run(ctx context.Context, url string) (ScreenerResult, error) {
+	if s.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opts.Timeout)
+		defer cancel()
+	}
 
-	// Start Chrome
-	// Ignore ssl errors
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("ignore-certificate-errors", "1"),
-	)
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	defer cancel()
-	// Remove the 2nd param if you don't need debug information logged
-	ctx, cancel := chromedp.NewContext(allocCtx)
+	tabCtx, cancel, err := s.pool.NewTab(ctx)
+	if err != nil {
+		return *NewScreenerResult(nil), err
+	}
 	defer cancel()
 
 	// Run Tasks
 	// List of actions to run in sequence (which also fills our image buffer)
 	var imageBuf []byte
-	if err := chromedp.Run(ctx, screenshotTasks(url, &imageBuf)); err != nil {
-		return *NewScreenerResult(""), err
+	if err := chromedp.Run(tabCtx, s.screenshotTasks(tabCtx, url, &imageBuf)); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return *NewScreenerResult(nil), err
 	}
 
-	// Generate b64 image
-	content := base64.StdEncoding.EncodeToString(imageBuf)
+	// Screenshots which cannot be hashed are only left out of the similarity groups
+	res := *NewScreenerResult(imageBuf)
+	res.Hash, _ = PerceptualHash(imageBuf)
+	return res, nil
+}
 
-	return *NewScreenerResult(content), nil
+// idleListener : returns a channel closed when the network of the page being loaded is idle
+func idleListener(tabCtx context.Context) <-chan bool {
+	idle := make(chan bool)
+	var once sync.Once
+	navigating := false
+	chromedp.ListenTarget(tabCtx, func(ev interface{}) {
+		e, ok := ev.(*page.EventLifecycleEvent)
+		if !ok {
+			return
+		}
+		// Events of the blank page the tab opens with are ignored
+		switch e.Name {
+		case "init":
+			navigating = true
+		case "networkIdle":
+			if navigating {
+				once.Do(func() { close(idle) })
+			}
+		}
+	})
+	return idle
 }
 
-func screenshotTasks(url string, imageBuf *[]byte) chromedp.Tasks {
+func (s Screener) screenshotTasks(tabCtx context.Context, url string, imageBuf *[]byte) chromedp.Tasks {
+	idle := idleListener(tabCtx)
 	return chromedp.Tasks{
+		page.SetLifecycleEventsEnabled(true),
+		emulation.SetDeviceMetricsOverride(int64(s.opts.Width), int64(s.opts.Height), 1, false),
 		chromedp.Navigate(url),
-		chromedp.Sleep(5 * time.Second),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			select {
+			case <-idle:
+			case <-time.After(s.opts.IdleWait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return nil
+		}),
 		chromedp.ActionFunc(func(ctx context.Context) (err error) {
-			*imageBuf, err = page.CaptureScreenshot().WithQuality(90).Do(ctx)
+			capture := page.CaptureScreenshot().WithQuality(90)
+			if s.opts.FullPage {
+				_, _, contentSize, err := page.GetLayoutMetrics().Do(ctx)
+				if err != nil {
+					return err
+				}
+				width, height := int64(math.Ceil(contentSize.Width)), int64(math.Ceil(contentSize.Height))
+				err = emulation.SetDeviceMetricsOverride(width, height, 1, false).Do(ctx)
+				if err != nil {
+					return err
+				}
+				capture = capture.WithClip(&page.Viewport{
+					X:      contentSize.X,
+					Y:      contentSize.Y,
+					Width:  contentSize.Width,
+					Height: contentSize.Height,
+					Scale:  1,
+				})
+			}
+			*imageBuf, err = capture.Do(ctx)
 			return err
 		}),
 	}
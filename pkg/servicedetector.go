@@ -0,0 +1,250 @@
+package pkg
+
+import (
+	"context"
+	"crypto/tls"
+	_ "embed"
+	"encoding/json"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxBannerLength : maximum length of the banners kept
+const maxBannerLength = 256
+
+//go:embed services.json
+var servicesJSON []byte
+
+// Service : service detected on a port
+type Service struct {
+	Port    int    `bson:"port" json:"port"`
+	Name    string `bson:"name" json:"name"`
+	Product string `bson:"product" json:"product"`
+	Version string `bson:"version" json:"version"`
+	TLS     bool   `bson:"tls" json:"tls"`
+	Banner  string `bson:"banner" json:"banner"`
+}
+
+type serviceProbe struct {
+	Name    string `json:"name"`
+	Payload string `json:"payload"`
+	// Ports are the ports the service of the probe usually listens on, the probe is sent first to them
+	Ports []int `json:"ports"`
+}
+
+type serviceSignature struct {
+	Probe   string `json:"probe"`
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Product string `json:"product"`
+	Version string `json:"version"`
+	regexp  *regexp.Regexp
+}
+
+type serviceDatabase struct {
+	Probes     []serviceProbe     `json:"probes"`
+	Signatures []serviceSignature `json:"signatures"`
+}
+
+var services serviceDatabase
+
+func init() {
+	err := json.Unmarshal(servicesJSON, &services)
+	if err != nil {
+		panic(err)
+	}
+	for idx := range services.Signatures {
+		services.Signatures[idx].regexp = regexp.MustCompile(services.Signatures[idx].Pattern)
+	}
+}
+
+// ServiceDetector : struct for service detection
+type ServiceDetector struct {
+	host    string
+	timeout time.Duration
+	threads int
+}
+
+// NewServiceDetector : returns new ServiceDetector struct
+func NewServiceDetector(host string, timeout time.Duration, threads int) *ServiceDetector {
+	return &ServiceDetector{host, timeout, threads}
+}
+
+// match : returns the service described by the first signature of a probe matching a response
+func match(probe string, response []byte) (Service, bool) {
+	for _, sig := range services.Signatures {
+		if sig.Probe != probe {
+			continue
+		}
+		loc := sig.regexp.FindSubmatchIndex(response)
+		if loc == nil {
+			continue
+		}
+		return Service{
+			Name:    sig.Name,
+			Product: string(sig.regexp.Expand(nil, []byte(sig.Product), response, loc)),
+			Version: string(sig.regexp.Expand(nil, []byte(sig.Version), response, loc)),
+		}, true
+	}
+	return Service{}, false
+}
+
+// probeOrder : returns the probes in the order they are sent to a port, the probes of the services usually listening
+// on the port come first and the others keep their order
+func probeOrder(port int) []serviceProbe {
+	hinted := make([]serviceProbe, 0)
+	others := make([]serviceProbe, 0)
+probes:
+	for _, probe := range services.Probes {
+		for _, p := range probe.Ports {
+			if p == port {
+				hinted = append(hinted, probe)
+				continue probes
+			}
+		}
+		others = append(others, probe)
+	}
+	return append(hinted, others...)
+}
+
+// banner : returns a printable excerpt of a response
+func banner(response []byte) string {
+	var b strings.Builder
+	for _, r := range string(response) {
+		if b.Len() >= maxBannerLength {
+			break
+		}
+		if r == '\n' || r == '\r' || r == '\t' || (strconv.IsPrint(r) && r != utf8.RuneError) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('.')
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// exchange : sends a payload and returns what the server answered before the timeout
+func (s ServiceDetector) exchange(ctx context.Context, port int, useTLS bool, payload string) ([]byte, error) {
+	d := net.Dialer{Timeout: s.timeout}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(s.host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < s.timeout {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(s.timeout))
+	}
+
+	if useTLS {
+		tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: s.host})
+		err = tlsConn.HandshakeContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	if payload != "" {
+		_, err = conn.Write([]byte(payload))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if n > 0 {
+		return buf[:n], nil
+	}
+	return nil, err
+}
+
+// isTLS : checks if a port speaks TLS
+func (s ServiceDetector) isTLS(ctx context.Context, port int) bool {
+	d := net.Dialer{Timeout: s.timeout}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(s.host, strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(s.timeout))
+
+	tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: s.host})
+	return tlsConn.HandshakeContext(ctx) == nil
+}
+
+// Detect : identifies the service listening on a port, its name is empty if it is unknown. Services speaking first
+// are identified from their banner, unless a probe of the services usually listening on the port matches before
+func (s ServiceDetector) Detect(ctx context.Context, port int) Service {
+	service := Service{Port: port}
+	checkedTLS := false
+	for _, probe := range probeOrder(port) {
+		if ctx.Err() != nil {
+			break
+		}
+		useTLS := false
+		if probe.Payload != "" {
+			if !checkedTLS {
+				service.TLS = s.isTLS(ctx, port)
+				checkedTLS = true
+			}
+			useTLS = service.TLS
+		}
+		response, _ := s.exchange(ctx, port, useTLS, probe.Payload)
+		if len(response) == 0 {
+			continue
+		}
+		if service.Banner == "" {
+			service.Banner = banner(response)
+		}
+		if found, ok := match(probe.Name, response); ok {
+			found.Port = port
+			found.TLS = useTLS
+			found.Banner = banner(response)
+			return found
+		}
+	}
+
+	if service.TLS {
+		service.Name = "tls"
+	}
+	return service
+}
+
+// Run : detects the services of a list of ports, it stops early when ctx is done
+func (s ServiceDetector) Run(ctx context.Context, ports []int) []Service {
+	rv := make([]Service, len(ports))
+	var wg sync.WaitGroup
+	sem := make(chan bool, s.threads)
+loop:
+	for idx, port := range ports {
+		select {
+		case <-ctx.Done():
+			break loop
+		case sem <- true:
+		}
+		wg.Add(1)
+		go func(idx int, port int) {
+			defer wg.Done()
+			rv[idx] = s.Detect(ctx, port)
+			<-sem
+		}(idx, port)
+	}
+	wg.Wait()
+
+	res := make([]Service, 0, len(rv))
+	for _, service := range rv {
+		if service.Port != 0 {
+			res = append(res, service)
+		}
+	}
+	return res
+}
@@ -0,0 +1,26 @@
+package pkg
+
+import "testing"
+
+func TestProbeOrder(t *testing.T) {
+	tests := []struct {
+		port int
+		want []string
+	}{
+		{22, []string{"null", "redis", "memcached", "http"}},
+		{8080, []string{"http", "null", "redis", "memcached"}},
+		{6379, []string{"redis", "null", "memcached", "http"}},
+	}
+	for _, tt := range tests {
+		probes := probeOrder(tt.port)
+		if len(probes) != len(tt.want) {
+			t.Errorf("port %d : got %d probes, want %d", tt.port, len(probes), len(tt.want))
+			continue
+		}
+		for idx, name := range tt.want {
+			if probes[idx].Name != name {
+				t.Errorf("port %d : got probe %s at %d, want %s", tt.port, probes[idx].Name, idx, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Formats of subdomain datasets
+const (
+	FormatList  = "list"
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// subdomainKeys : fields of JSON lines and columns of CSV holding the names, by order of preference
+var subdomainKeys = []string{"name", "host", "hostname", "subdomain", "domain", "name_value", "common_name"}
+
+var hostnameRegexp = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)+$`)
+
+// ErrUnknownFormat is returned for a dataset format which is not supported
+var ErrUnknownFormat = errors.New("unknown format, expected list, jsonl or csv")
+
+// ParseSubdomains : returns the distinct subdomains of domain listed in a dataset, the format is guessed when it is
+// empty. Names out of the domain are left out and wildcard labels are removed
+func ParseSubdomains(r io.Reader, format, domain string) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format = guessFormat(data)
+	}
+
+	var names []string
+	switch format {
+	case FormatList:
+		names = parseList(data)
+	case FormatJSONL:
+		names, err = parseJSONLines(data)
+	case FormatCSV:
+		names, err = parseCSV(data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := "." + strings.ToLower(strings.TrimSuffix(domain, "."))
+	seen := make(map[string]bool)
+	res := make([]string, 0)
+	for _, name := range names {
+		name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+		for strings.HasPrefix(name, "*.") {
+			name = strings.TrimPrefix(name, "*.")
+		}
+		if !strings.HasSuffix(name, suffix) || !hostnameRegexp.MatchString(name) || seen[name] {
+			continue
+		}
+		seen[name] = true
+		res = append(res, name)
+	}
+	return res, nil
+}
+
+// guessFormat : guesses the format of a dataset from its first line
+func guessFormat(data []byte) string {
+	line := firstLine(data)
+	switch {
+	case strings.HasPrefix(line, "{") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, `"`):
+		return FormatJSONL
+	case strings.Contains(line, ","):
+		return FormatCSV
+	}
+	return FormatList
+}
+
+func firstLine(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// parseList : one name per line, anything after the first field and comments are ignored
+func parseList(data []byte) []string {
+	names := make([]string, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && !strings.HasPrefix(fields[0], "#") {
+			names = append(names, fields[0])
+		}
+	}
+	return names
+}
+
+// parseJSONLines : one JSON object or string per line, a single JSON array such as the output of crt.sh is accepted too
+func parseJSONLines(data []byte) ([]string, error) {
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		var values []interface{}
+		err := json.Unmarshal(data, &values)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0)
+		for _, value := range values {
+			names = append(names, jsonNames(value)...)
+		}
+		return names, nil
+	}
+
+	names := make([]string, 0)
+	for idx, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var value interface{}
+		err := json.Unmarshal([]byte(line), &value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d : %w", idx+1, err)
+		}
+		names = append(names, jsonNames(value)...)
+	}
+	return names, nil
+}
+
+// jsonNames : returns the names held by a JSON value, a field may hold several names separated by new lines
+func jsonNames(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case map[string]interface{}:
+		for _, key := range subdomainKeys {
+			if s, ok := v[key].(string); ok && s != "" {
+				return strings.Fields(s)
+			}
+		}
+	}
+	return nil
+}
+
+// parseCSV : the names are in the column with a known header, in the first column when there is no header
+func parseCSV(data []byte) ([]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return make([]string, 0), nil
+	}
+
+	column := 0
+	if idx := headerColumn(records[0]); idx >= 0 {
+		column = idx
+		records = records[1:]
+	}
+	names := make([]string, 0)
+	for _, record := range records {
+		if column < len(record) {
+			names = append(names, strings.Fields(record[column])...)
+		}
+	}
+	return names, nil
+}
+
+// headerColumn : returns the column of a header holding the names, -1 when the row is not a header
+func headerColumn(row []string) int {
+	for _, key := range subdomainKeys {
+		for idx, cell := range row {
+			if strings.EqualFold(strings.TrimSpace(cell), key) {
+				return idx
+			}
+		}
+	}
+	return -1
+}
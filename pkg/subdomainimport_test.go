@@ -0,0 +1,47 @@
+package pkg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseSubdomains(t *testing.T) {
+	tests := []struct {
+		desc   string
+		format string
+		data   string
+		want   []string
+	}{
+		{"list", FormatList, "www.example.com\n# comment\n\napi.example.com 10.0.0.1\n", []string{"www.example.com", "api.example.com"}},
+		{"guessed list", "", "WWW.Example.com.\nwww.example.com\n", []string{"www.example.com"}},
+		{"wildcards and other domains", "", "*.*.dev.example.com\nexample.org\nnotexample.com\nexample.com\nbad name.example.com\n", []string{"dev.example.com"}},
+		{"jsonl", FormatJSONL, `{"host": "a.example.com", "ip": "10.0.0.1"}` + "\n\n" + `"b.example.com"` + "\n" + `{"port": 80}`, []string{"a.example.com", "b.example.com"}},
+		{"guessed jsonl", "", `{"name": "a.example.com"}`, []string{"a.example.com"}},
+		{"crt.sh", "", `[{"common_name": "example.com", "name_value": "mail.example.com\n*.cdn.example.com"}, {"common_name": "vpn.example.com"}]`, []string{"mail.example.com", "cdn.example.com", "vpn.example.com"}},
+		{"csv with header", FormatCSV, "ip,Hostname\n10.0.0.1,a.example.com\n10.0.0.2\n", []string{"a.example.com"}},
+		{"guessed csv without header", "", "a.example.com,10.0.0.1\nb.example.com,10.0.0.2\n", []string{"a.example.com", "b.example.com"}},
+		{"empty csv", FormatCSV, "", []string{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSubdomains(strings.NewReader(tt.data), tt.format, "example.com.")
+		if err != nil {
+			t.Errorf("%s : %v", tt.desc, err)
+			continue
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s : got %v, want %v", tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestParseSubdomainsErrors(t *testing.T) {
+	_, err := ParseSubdomains(strings.NewReader("www.example.com"), "xml", "example.com")
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("got %v for an unknown format, want %v", err, ErrUnknownFormat)
+	}
+	_, err = ParseSubdomains(strings.NewReader(`{"name": "a.example.com"}`+"\n{broken"), FormatJSONL, "example.com")
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2 : ") {
+		t.Errorf("got %v for a broken JSON line, want an error on line 2", err)
+	}
+}
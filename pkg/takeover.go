@@ -0,0 +1,124 @@
+package pkg
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//go:embed takeovers.json
+var takeoversJSON []byte
+
+// Takeover : evidence that a subdomain can be taken over through a third-party service
+type Takeover struct {
+	Service  string `bson:"service" json:"service"`
+	CNAME    string `bson:"cname" json:"cname"`
+	Evidence string `bson:"evidence" json:"evidence"`
+}
+
+// takeoverService : a third-party service with the CNAME patterns pointing to it and the signs of an unclaimed name
+type takeoverService struct {
+	Name         string   `json:"name"`
+	CNAMEs       []string `json:"cnames"`
+	Fingerprints []string `json:"fingerprints"`
+	// NXDomain is set when an unclaimed name does not exist at the service
+	NXDomain bool `json:"nxdomain"`
+
+	cnames []*regexp.Regexp
+}
+
+var takeoverServices []*takeoverService
+
+func init() {
+	var doc struct {
+		Services []*takeoverService `json:"services"`
+	}
+	err := json.Unmarshal(takeoversJSON, &doc)
+	if err != nil {
+		panic(err)
+	}
+	for _, s := range doc.Services {
+		for _, pattern := range s.CNAMEs {
+			s.cnames = append(s.cnames, regexp.MustCompile("(?i)"+pattern))
+		}
+	}
+	takeoverServices = doc.Services
+}
+
+func (s *takeoverService) matches(cname string) bool {
+	for _, re := range s.cnames {
+		if re.MatchString(cname) {
+			return true
+		}
+	}
+	return false
+}
+
+// TakeoverChecker : struct for detecting dangling CNAME records
+type TakeoverChecker struct {
+	client  *DNSClient
+	timeout time.Duration
+}
+
+// NewTakeoverChecker : returns new TakeoverChecker resolving CNAME targets with client
+func NewTakeoverChecker(client *DNSClient, timeout time.Duration) *TakeoverChecker {
+	return &TakeoverChecker{client, timeout}
+}
+
+// Check : returns the takeover a subdomain is exposed to given its records, nil when it is not
+func (t TakeoverChecker) Check(ctx context.Context, name string, records []DNSRecord) *Takeover {
+	for _, cname := range RecordValues(records, "CNAME") {
+		for _, s := range takeoverServices {
+			if !s.matches(cname) {
+				continue
+			}
+			if s.NXDomain {
+				exists, err := t.client.Exists(ctx, cname)
+				if err == nil && !exists {
+					return &Takeover{Service: s.Name, CNAME: cname, Evidence: cname + " does not exist"}
+				}
+				continue
+			}
+			if fingerprint := t.fingerprint(ctx, name, s); fingerprint != "" {
+				return &Takeover{Service: s.Name, CNAME: cname, Evidence: fingerprint}
+			}
+		}
+	}
+	return nil
+}
+
+// fingerprint : returns the fingerprint of an unclaimed name of a service found in the pages of a subdomain
+func (t TakeoverChecker) fingerprint(ctx context.Context, name string, s *takeoverService) string {
+	for _, proto := range []string{"http://", "https://"} {
+		body, err := t.get(ctx, proto+name)
+		if err != nil {
+			continue
+		}
+		for _, fingerprint := range s.Fingerprints {
+			if strings.Contains(string(body), fingerprint) {
+				return fingerprint
+			}
+		}
+	}
+	return ""
+}
+
+func (t TakeoverChecker) get(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := InsecureClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+}
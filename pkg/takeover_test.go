@@ -0,0 +1,107 @@
+package pkg
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// recordServer : serves over UDP the A records of names, NXDOMAIN for any other name
+func recordServer(t *testing.T, names map[string][4]byte) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var m dnsmessage.Message
+			if m.Unpack(buf[:n]) != nil || len(m.Questions) != 1 {
+				continue
+			}
+			m.Header.Response = true
+			q := m.Questions[0]
+			a, ok := names[strings.TrimSuffix(q.Name.String(), ".")]
+			switch {
+			case !ok:
+				m.Header.RCode = dnsmessage.RCodeNameError
+			case q.Type == dnsmessage.TypeA:
+				m.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300},
+					Body:   &dnsmessage.AResource{A: a},
+				}}
+			}
+			answer, err := m.Pack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			conn.WriteTo(answer, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestTakeoverCheckerCheck(t *testing.T) {
+	client, err := NewDNSClient(recordServer(t, map[string][4]byte{"claimed.azurewebsites.net": {10, 0, 0, 1}}), 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unclaimed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("<p>There isn't a GitHub Pages site here.</p>"))
+	}))
+	defer unclaimed.Close()
+	claimed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<p>Welcome</p>"))
+	}))
+	defer claimed.Close()
+
+	cname := func(target string) []DNSRecord {
+		return []DNSRecord{{Name: "www.example.test", Type: "CNAME", TTL: 300, Value: target}}
+	}
+	tests := []struct {
+		desc     string
+		name     string
+		records  []DNSRecord
+		service  string
+		evidence string
+	}{
+		{"dangling target", "www.example.test", cname("gone.azurewebsites.net"), "Microsoft Azure", "gone.azurewebsites.net does not exist"},
+		{"existing target", "www.example.test", cname("claimed.azurewebsites.net"), "", ""},
+		{"unknown service", "www.example.test", cname("gone.example.org"), "", ""},
+		{"no CNAME", "www.example.test", []DNSRecord{{Name: "www.example.test", Type: "A", Value: "10.0.0.2"}}, "", ""},
+		{"unclaimed page", strings.TrimPrefix(unclaimed.URL, "http://"), cname("example.github.io"), "GitHub Pages", "There isn't a GitHub Pages site here."},
+		{"claimed page", strings.TrimPrefix(claimed.URL, "http://"), cname("example.github.io"), "", ""},
+	}
+
+	checker := NewTakeoverChecker(client, 2*time.Second)
+	for _, tt := range tests {
+		takeover := checker.Check(context.Background(), tt.name, tt.records)
+		if tt.service == "" {
+			if takeover != nil {
+				t.Errorf("%s : got takeover %+v, want none", tt.desc, *takeover)
+			}
+			continue
+		}
+		if takeover == nil {
+			t.Errorf("%s : got no takeover, want %s", tt.desc, tt.service)
+			continue
+		}
+		if takeover.Service != tt.service || takeover.CNAME != tt.records[0].Value || takeover.Evidence != tt.evidence {
+			t.Errorf("%s : got takeover %+v, want %s with evidence %q", tt.desc, *takeover, tt.service, tt.evidence)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package pkg
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ParseTarget returns the IP address of a target, nil if it is a host name,
+// IPv6 addresses may be enclosed in brackets
+func ParseTarget(target string) net.IP {
+	return net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(target, "["), "]"))
+}
+
+// IsNetworkTarget tells whether a target designates several addresses
+func IsNetworkTarget(target string) bool {
+	return strings.Contains(target, "/") || (strings.Contains(target, "-") && ParseTarget(strings.SplitN(target, "-", 2)[0]) != nil)
+}
+
+// ExpandTarget returns the addresses designated by a target : a host name, an IP address,
+// a CIDR network (10.0.0.0/24, without its network and broadcast addresses) or a range
+// (192.168.1.10-50 or 192.168.1.10-192.168.2.5), it fails if there are more than max addresses
+func ExpandTarget(target string, max int) ([]string, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, fmt.Errorf("empty target")
+	}
+
+	if strings.Contains(target, "/") {
+		_, network, err := net.ParseCIDR(target)
+		if err != nil {
+			return nil, err
+		}
+		first := network.IP
+		ones, bits := network.Mask.Size()
+		count := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+		last := addIP(first, new(big.Int).Sub(count, big.NewInt(1)))
+		// The network and broadcast addresses of IPv4 networks are not hosts, /31 and /32 have neither
+		if first.To4() != nil && bits-ones > 1 {
+			first = addIP(first, big.NewInt(1))
+			last = addIP(last, big.NewInt(-1))
+		}
+		return expandRange(first, last, max)
+	}
+
+	if ip := ParseTarget(target); ip != nil {
+		return []string{ip.String()}, nil
+	}
+
+	if parts := strings.SplitN(target, "-", 2); len(parts) == 2 {
+		first := ParseTarget(parts[0])
+		if first != nil {
+			last := ParseTarget(parts[1])
+			if last == nil {
+				// Only the last part of the address is given
+				end, err := strconv.ParseUint(parts[1], 10, 8)
+				if err != nil || first.To4() == nil {
+					return nil, fmt.Errorf("invalid range %s", target)
+				}
+				last = make(net.IP, net.IPv4len)
+				copy(last, first.To4())
+				last[3] = byte(end)
+			}
+			return expandRange(first, last, max)
+		}
+	}
+
+	// Host name
+	return []string{target}, nil
+}
+
+func expandRange(first net.IP, last net.IP, max int) ([]string, error) {
+	if (first.To4() == nil) != (last.To4() == nil) {
+		return nil, fmt.Errorf("range mixes IPv4 and IPv6 addresses")
+	}
+	if first.To4() != nil {
+		first, last = first.To4(), last.To4()
+	}
+
+	start := new(big.Int).SetBytes(first)
+	end := new(big.Int).SetBytes(last)
+	if start.Cmp(end) > 0 {
+		return nil, fmt.Errorf("range starts after it ends")
+	}
+	count := new(big.Int).Sub(end, start)
+	count.Add(count, big.NewInt(1))
+	if count.Cmp(big.NewInt(int64(max))) > 0 {
+		return nil, fmt.Errorf("target has %s addresses, the maximum is %d", count.String(), max)
+	}
+
+	ret := make([]string, 0, count.Int64())
+	for i := int64(0); i < count.Int64(); i++ {
+		ret = append(ret, addIP(first, big.NewInt(i)).String())
+	}
+	return ret, nil
+}
+
+func addIP(ip net.IP, n *big.Int) net.IP {
+	sum := new(big.Int).Add(new(big.Int).SetBytes(ip), n)
+	b := sum.Bytes()
+	res := make(net.IP, len(ip))
+	copy(res[len(res)-len(b):], b)
+	return res
+}
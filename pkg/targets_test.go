@@ -0,0 +1,84 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		max    int
+		want   []string
+	}{
+		{"example.com", 10, []string{"example.com"}},
+		{" 10.0.0.1 ", 10, []string{"10.0.0.1"}},
+		{"[2001:db8::1]", 10, []string{"2001:db8::1"}},
+		{"10.0.0.0/30", 10, []string{"10.0.0.1", "10.0.0.2"}},
+		{"10.0.0.5/29", 10, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"}},
+		{"10.0.0.0/31", 10, []string{"10.0.0.0", "10.0.0.1"}},
+		{"10.0.0.7/32", 10, []string{"10.0.0.7"}},
+		{"2001:db8::/126", 10, []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3"}},
+		{"192.168.1.254-192.168.2.1", 10, []string{"192.168.1.254", "192.168.1.255", "192.168.2.0", "192.168.2.1"}},
+		{"192.168.1.10-12", 10, []string{"192.168.1.10", "192.168.1.11", "192.168.1.12"}},
+		{"2001:db8::ff-2001:db8::101", 10, []string{"2001:db8::ff", "2001:db8::100", "2001:db8::101"}},
+		{"10.0.0.0/24", 254, nil},
+	}
+	for _, tt := range tests {
+		got, err := ExpandTarget(tt.target, tt.max)
+		if err != nil {
+			t.Errorf("%s : %v", tt.target, err)
+			continue
+		}
+		if tt.want == nil {
+			if len(got) != tt.max || got[0] != "10.0.0.1" || got[len(got)-1] != "10.0.0.254" {
+				t.Errorf("%s : got %d addresses from %s to %s, want 10.0.0.1 to 10.0.0.254", tt.target, len(got), got[0], got[len(got)-1])
+			}
+			continue
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s : got %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestExpandTargetErrors(t *testing.T) {
+	tests := []struct {
+		target string
+		max    int
+	}{
+		{"", 10},
+		{"10.0.0.0/33", 10},
+		{"10.0.0.0/24", 253},
+		{"2001:db8::/64", 1000},
+		{"10.0.0.5-3", 10},
+		{"10.0.0.1-300", 10},
+		{"2001:db8::1-5", 10},
+		{"10.0.0.1-2001:db8::1", 10},
+	}
+	for _, tt := range tests {
+		got, err := ExpandTarget(tt.target, tt.max)
+		if err == nil {
+			t.Errorf("%s : got %d addresses, want an error", tt.target, len(got))
+		}
+	}
+}
+
+func TestIsNetworkTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"10.0.0.0/24", true},
+		{"10.0.0.1-20", true},
+		{"2001:db8::1-2001:db8::5", true},
+		{"10.0.0.1", false},
+		{"my-host.example.com", false},
+		{"[2001:db8::1]", false},
+	}
+	for _, tt := range tests {
+		if got := IsNetworkTarget(tt.target); got != tt.want {
+			t.Errorf("IsNetworkTarget(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
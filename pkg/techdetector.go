@@ -0,0 +1,416 @@
+package pkg
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxPageSize : maximum number of bytes of a page read for fingerprinting
+const maxPageSize = 2 << 20
+
+//go:embed technologies.json
+var technologiesJSON []byte
+
+// Technology : technology detected on a web page
+type Technology struct {
+	Name       string   `bson:"name" json:"name"`
+	Categories []string `bson:"categories" json:"categories"`
+	Version    string   `bson:"version" json:"version"`
+}
+
+// techPattern : a regex with the Wappalyzer version template following "\;version:"
+type techPattern struct {
+	regexp  *regexp.Regexp
+	version string
+}
+
+type techDefinition struct {
+	Cats      categoryList             `json:"cats"`
+	Headers   map[string]stringOrSlice `json:"headers"`
+	Cookies   map[string]stringOrSlice `json:"cookies"`
+	Meta      map[string]stringOrSlice `json:"meta"`
+	ScriptSrc stringOrSlice            `json:"scriptSrc"`
+	HTML      stringOrSlice            `json:"html"`
+	Implies   stringOrSlice            `json:"implies"`
+
+	headers   map[string][]techPattern
+	cookies   map[string][]techPattern
+	meta      map[string][]techPattern
+	scriptSrc []techPattern
+	html      []techPattern
+}
+
+// techCategory : a category of a Wappalyzer categories document, keyed by its numeric ID
+type techCategory struct {
+	Name string `json:"name"`
+}
+
+// stringOrSlice : Wappalyzer files use either a string or a list of strings
+type stringOrSlice []string
+
+func (s *stringOrSlice) UnmarshalJSON(data []byte) error {
+	var one string
+	if json.Unmarshal(data, &one) == nil {
+		*s = []string{one}
+		return nil
+	}
+	var many []string
+	err := json.Unmarshal(data, &many)
+	*s = many
+	return err
+}
+
+// categoryList : categories are names in the bundled file and numeric IDs in the upstream Wappalyzer files
+type categoryList []string
+
+func (c *categoryList) UnmarshalJSON(data []byte) error {
+	var values []interface{}
+	err := json.Unmarshal(data, &values)
+	if err != nil {
+		return err
+	}
+	*c = make([]string, 0, len(values))
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			*c = append(*c, v)
+		case float64:
+			*c = append(*c, strconv.Itoa(int(v)))
+		default:
+			return fmt.Errorf("invalid category %v", value)
+		}
+	}
+	return nil
+}
+
+var (
+	technologiesMu sync.RWMutex
+	technologies   map[string]*techDefinition
+	// categories : names of the numeric categories, by ID
+	categories = make(map[string]string)
+
+	metaRegexp      = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	metaNameRegexp  = regexp.MustCompile(`(?is)(?:name|property)\s*=\s*["']([^"']+)["']`)
+	metaValueRegexp = regexp.MustCompile(`(?is)content\s*=\s*["']([^"']*)["']`)
+	scriptRegexp    = regexp.MustCompile(`(?is)<script[^>]+src\s*=\s*["']([^"']+)["']`)
+	templateRegexp  = regexp.MustCompile(`\\(\d)(?:\?([^:]*):(.*))?`)
+)
+
+func init() {
+	err := ParseTechnologies(technologiesJSON)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newTechPattern(s string) (techPattern, error) {
+	parts := strings.Split(s, `\;`)
+	p := techPattern{}
+	for _, part := range parts[1:] {
+		if strings.HasPrefix(part, "version:") {
+			p.version = strings.TrimPrefix(part, "version:")
+		}
+	}
+	re, err := regexp.Compile("(?i)" + parts[0])
+	if err != nil {
+		return p, err
+	}
+	p.regexp = re
+	return p, nil
+}
+
+// newTechPatternList : compiles patterns, the ones Go cannot compile such as lookaheads are logged and skipped
+func newTechPatternList(technology string, patterns []string) []techPattern {
+	res := make([]techPattern, 0, len(patterns))
+	for _, s := range patterns {
+		p, err := newTechPattern(s)
+		if err != nil {
+			log.Printf("[-] Pattern of %s skipped : %v", technology, err)
+			continue
+		}
+		res = append(res, p)
+	}
+	return res
+}
+
+// newTechPatterns : compiles patterns keyed by case insensitive names
+func newTechPatterns(technology string, m map[string]stringOrSlice) map[string][]techPattern {
+	res := make(map[string][]techPattern)
+	for k, v := range m {
+		if patterns := newTechPatternList(technology, v); len(patterns) > 0 {
+			res[strings.ToLower(k)] = patterns
+		}
+	}
+	return res
+}
+
+// ParseCategories : sets the names of the numeric categories of the technologies parsed afterwards from a
+// Wappalyzer categories document
+func ParseCategories(data []byte) error {
+	var doc map[string]techCategory
+	err := json.Unmarshal(data, &doc)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]string)
+	for id, c := range doc {
+		names[id] = c.Name
+	}
+
+	technologiesMu.Lock()
+	categories = names
+	technologiesMu.Unlock()
+	return nil
+}
+
+// LoadCategories : sets the names of the numeric categories from a file
+func LoadCategories(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return ParseCategories(data)
+}
+
+// ParseTechnologies : replaces the signatures of technologies by the ones of a Wappalyzer-style JSON document, either
+// an object with technologies and categories fields or an upstream file holding only technologies. Numeric categories
+// are named after the categories loaded, patterns which do not compile are skipped
+func ParseTechnologies(data []byte) error {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	var doc struct {
+		Technologies map[string]*techDefinition `json:"technologies"`
+		Categories   map[string]techCategory    `json:"categories"`
+	}
+	if _, ok := fields["technologies"]; ok {
+		err = json.Unmarshal(data, &doc)
+	} else {
+		err = json.Unmarshal(data, &doc.Technologies)
+	}
+	if err != nil {
+		return err
+	}
+
+	technologiesMu.RLock()
+	names := make(map[string]string)
+	for id, name := range categories {
+		names[id] = name
+	}
+	technologiesMu.RUnlock()
+	for id, c := range doc.Categories {
+		names[id] = c.Name
+	}
+
+	for name, def := range doc.Technologies {
+		for idx, cat := range def.Cats {
+			if n, ok := names[cat]; ok {
+				def.Cats[idx] = n
+			}
+		}
+		def.headers = newTechPatterns(name, def.Headers)
+		def.cookies = newTechPatterns(name, def.Cookies)
+		def.meta = newTechPatterns(name, def.Meta)
+		def.scriptSrc = newTechPatternList(name, def.ScriptSrc)
+		def.html = newTechPatternList(name, def.HTML)
+	}
+
+	technologiesMu.Lock()
+	technologies = doc.Technologies
+	technologiesMu.Unlock()
+	return nil
+}
+
+// LoadTechnologies : replaces the bundled signatures of technologies by the ones of a file
+func LoadTechnologies(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return ParseTechnologies(data)
+}
+
+// match : returns whether a pattern matches a value and the version it extracts
+func (p techPattern) match(value string) (bool, string) {
+	groups := p.regexp.FindStringSubmatch(value)
+	if groups == nil {
+		return false, ""
+	}
+	if p.version == "" {
+		return true, ""
+	}
+	version := templateRegexp.ReplaceAllStringFunc(p.version, func(ref string) string {
+		sub := templateRegexp.FindStringSubmatch(ref)
+		idx := int(sub[1][0] - '0')
+		value := ""
+		if idx < len(groups) {
+			value = groups[idx]
+		}
+		if !strings.Contains(ref, "?") {
+			return value
+		}
+		// Ternary : \1?yes:no
+		if value != "" {
+			return sub[2]
+		}
+		return sub[3]
+	})
+	return true, version
+}
+
+// techPage : parts of a page technologies are matched against
+type techPage struct {
+	headers map[string][]string
+	cookies map[string]string
+	meta    map[string]string
+	scripts []string
+	html    string
+}
+
+func newTechPage(header http.Header, body []byte) techPage {
+	p := techPage{
+		headers: make(map[string][]string),
+		cookies: make(map[string]string),
+		meta:    make(map[string]string),
+		html:    string(body),
+	}
+	for k, v := range header {
+		p.headers[strings.ToLower(k)] = v
+	}
+	for _, c := range (&http.Response{Header: header}).Cookies() {
+		p.cookies[strings.ToLower(c.Name)] = c.Value
+	}
+	for _, tag := range metaRegexp.FindAllString(p.html, -1) {
+		name := metaNameRegexp.FindStringSubmatch(tag)
+		value := metaValueRegexp.FindStringSubmatch(tag)
+		if name != nil && value != nil {
+			p.meta[strings.ToLower(name[1])] = value[1]
+		}
+	}
+	for _, src := range scriptRegexp.FindAllStringSubmatch(p.html, -1) {
+		p.scripts = append(p.scripts, src[1])
+	}
+	return p
+}
+
+// detect : returns whether a technology is used by a page and its version when found
+func (def *techDefinition) detect(p techPage) (bool, string) {
+	found := false
+	version := ""
+	check := func(pattern techPattern, value string) {
+		if ok, v := pattern.match(value); ok {
+			found = true
+			if len(v) > len(version) {
+				version = v
+			}
+		}
+	}
+
+	for name, patterns := range def.headers {
+		for _, value := range p.headers[name] {
+			for _, pattern := range patterns {
+				check(pattern, value)
+			}
+		}
+	}
+	for name, patterns := range def.cookies {
+		if value, ok := p.cookies[name]; ok {
+			for _, pattern := range patterns {
+				check(pattern, value)
+			}
+		}
+	}
+	for name, patterns := range def.meta {
+		if value, ok := p.meta[name]; ok {
+			for _, pattern := range patterns {
+				check(pattern, value)
+			}
+		}
+	}
+	for _, pattern := range def.scriptSrc {
+		for _, src := range p.scripts {
+			check(pattern, src)
+		}
+	}
+	for _, pattern := range def.html {
+		check(pattern, p.html)
+	}
+	return found, version
+}
+
+// DetectTechnologies : returns the technologies used by a page from its headers and body
+func DetectTechnologies(header http.Header, body []byte) []Technology {
+	technologiesMu.RLock()
+	defer technologiesMu.RUnlock()
+
+	p := newTechPage(header, body)
+	detected := make(map[string]*Technology)
+	for name, def := range technologies {
+		if ok, version := def.detect(p); ok {
+			detected[name] = &Technology{Name: name, Categories: []string(def.Cats), Version: version}
+		}
+	}
+
+	// Add implied technologies until there is nothing new
+	for changed := true; changed; {
+		changed = false
+		for name := range detected {
+			for _, implied := range technologies[name].Implies {
+				implied = strings.Split(implied, `\;`)[0]
+				def, ok := technologies[implied]
+				if _, known := detected[implied]; ok && !known {
+					detected[implied] = &Technology{Name: implied, Categories: []string(def.Cats)}
+					changed = true
+				}
+			}
+		}
+	}
+
+	res := make([]Technology, 0, len(detected))
+	for _, t := range detected {
+		res = append(res, *t)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// TechDetector : struct for web technology fingerprinting
+type TechDetector struct{}
+
+// NewTechDetector : returns new TechDetector
+func NewTechDetector() *TechDetector {
+	return &TechDetector{}
+}
+
+// Run : fetches a page and returns the technologies it uses
+func (t TechDetector) Run(ctx context.Context, url string) ([]Technology, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := InsecureClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return nil, err
+	}
+	return DetectTechnologies(resp.Header, body), nil
+}
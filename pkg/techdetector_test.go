@@ -0,0 +1,45 @@
+package pkg
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseTechnologiesUpstream(t *testing.T) {
+	t.Cleanup(func() {
+		ParseCategories([]byte(`{}`))
+		ParseTechnologies(technologiesJSON)
+	})
+
+	err := ParseCategories([]byte(`{"1": {"name": "CMS", "priority": 1}, "22": {"name": "Web servers"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An upstream file holds only technologies, with numeric categories and patterns Go cannot compile
+	err = ParseTechnologies([]byte(`{
+		"WordPress": {
+			"cats": [1, 11],
+			"meta": {"generator": ["^WordPress ?([\\d.]+)?\\;version:\\1", "(?!x)WP"]},
+			"html": "<link rel=[\"']stylesheet[\"'] [^>]+/wp-(?:content|includes)/"
+		},
+		"Lookahead": {
+			"cats": [22],
+			"headers": {"Server": "^(?=lookahead)"}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte(`<html><head><meta name="generator" content="WordPress 6.1.1"></head></html>`)
+	res := DetectTechnologies(http.Header{"Server": []string{"lookahead"}}, body)
+	if len(res) != 1 {
+		t.Fatalf("got %v, want WordPress only", res)
+	}
+	if res[0].Name != "WordPress" || res[0].Version != "6.1.1" {
+		t.Errorf("got %+v, want WordPress 6.1.1", res[0])
+	}
+	if cats := res[0].Categories; len(cats) != 2 || cats[0] != "CMS" || cats[1] != "11" {
+		t.Errorf("got categories %v, want CMS and the unknown 11", cats)
+	}
+}
@@ -0,0 +1,112 @@
+package pkg
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Throttle spaces out requests so that no more than a given number are sent per second
+type Throttle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+	// rates counts the scans sharing the throttle of a host by the rate they asked for
+	rates map[int]int
+}
+
+var (
+	throttlesMu sync.Mutex
+	throttles   = make(map[string]*Throttle)
+)
+
+// NewThrottle returns a new Throttle allowing rate requests per second, 0 means unlimited
+func NewThrottle(rate int) *Throttle {
+	t := &Throttle{}
+	t.SetRate(rate)
+	return t
+}
+
+// HostThrottle returns the Throttle shared by every scan targeting host, limited to the strictest rate asked by
+// the scans using it. release must be called once the scan is done, the throttle of a host no scan uses is dropped
+func HostThrottle(host string, rate int) (t *Throttle, release func()) {
+	throttlesMu.Lock()
+	defer throttlesMu.Unlock()
+
+	t, ok := throttles[host]
+	if !ok {
+		t = &Throttle{rates: make(map[int]int)}
+		throttles[host] = t
+	}
+	t.rates[rate]++
+	t.SetRate(strictestRate(t.rates))
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			throttlesMu.Lock()
+			defer throttlesMu.Unlock()
+
+			t.rates[rate]--
+			if t.rates[rate] == 0 {
+				delete(t.rates, rate)
+			}
+			if len(t.rates) == 0 {
+				delete(throttles, host)
+				return
+			}
+			t.SetRate(strictestRate(t.rates))
+		})
+	}
+	return t, release
+}
+
+// strictestRate returns the lowest limited rate, 0 when every rate is unlimited
+func strictestRate(rates map[int]int) int {
+	strictest := 0
+	for rate := range rates {
+		if rate > 0 && (strictest == 0 || rate < strictest) {
+			strictest = rate
+		}
+	}
+	return strictest
+}
+
+// SetRate changes the number of requests allowed per second, 0 means unlimited
+func (t *Throttle) SetRate(rate int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.interval = 0
+	if rate > 0 {
+		t.interval = time.Second / time.Duration(rate)
+	}
+}
+
+// Wait blocks until a request may be sent or ctx is done
+func (t *Throttle) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	if t.interval == 0 {
+		t.mu.Unlock()
+		return ctx.Err()
+	}
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	delay := t.next.Sub(now)
+	t.next = t.next.Add(t.interval)
+	t.mu.Unlock()
+
+	if delay == 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestThrottleWait(t *testing.T) {
+	tests := []struct {
+		rate     int
+		requests int
+		min      time.Duration
+		max      time.Duration
+	}{
+		{0, 50, 0, 20 * time.Millisecond},
+		{100, 11, 100 * time.Millisecond, 300 * time.Millisecond},
+		{20, 3, 100 * time.Millisecond, 300 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		throttle := NewThrottle(tt.rate)
+		start := time.Now()
+		for i := 0; i < tt.requests; i++ {
+			err := throttle.Wait(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		elapsed := time.Since(start)
+		if elapsed < tt.min || elapsed > tt.max {
+			t.Errorf("%d requests at %d per second took %s, want between %s and %s", tt.requests, tt.rate, elapsed, tt.min, tt.max)
+		}
+	}
+}
+
+func TestThrottleWaitCancelled(t *testing.T) {
+	throttle := NewThrottle(1)
+	err := throttle.Wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = throttle.Wait(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("cancelled wait took %s", elapsed)
+	}
+}
+
+func TestHostThrottle(t *testing.T) {
+	const host = "throttle.test:80"
+	interval := func(throttle *Throttle) time.Duration {
+		throttle.mu.Lock()
+		defer throttle.mu.Unlock()
+		return throttle.interval
+	}
+
+	limited, releaseLimited := HostThrottle(host, 100)
+	unlimited, releaseUnlimited := HostThrottle(host, 0)
+	if limited != unlimited {
+		t.Fatal("scans of the same host got different throttles")
+	}
+	if got := interval(limited); got != 10*time.Millisecond {
+		t.Errorf("an unlimited scan changed the interval to %s, want 10ms", got)
+	}
+
+	_, releaseStrict := HostThrottle(host, 50)
+	if got := interval(limited); got != 20*time.Millisecond {
+		t.Errorf("got interval %s with a stricter scan, want 20ms", got)
+	}
+	releaseStrict()
+	releaseStrict()
+	if got := interval(limited); got != 10*time.Millisecond {
+		t.Errorf("got interval %s once the stricter scan is done, want 10ms", got)
+	}
+
+	releaseLimited()
+	if got := interval(limited); got != 0 {
+		t.Errorf("got interval %s with only an unlimited scan, want 0", got)
+	}
+
+	releaseUnlimited()
+	throttlesMu.Lock()
+	_, ok := throttles[host]
+	throttlesMu.Unlock()
+	if ok {
+		t.Error("the throttle of a host no scan uses was kept")
+	}
+}
@@ -0,0 +1,97 @@
+package pkg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	// Black left half and white right half
+	halves := func(x, y float64) uint8 {
+		if x < 0.5 {
+			return 0
+		}
+		return 255
+	}
+	var jpg bytes.Buffer
+	err := jpeg.Encode(&jpg, image.NewGray(image.Rect(0, 0, 800, 600)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		desc   string
+		data   []byte
+		width  int
+		height int
+	}{
+		{"screenshot", encodeImage(t, 1280, 960, halves), 320, 240},
+		{"narrow image", encodeImage(t, 100, 50, halves), 100, 50},
+		{"flat image", encodeImage(t, 2000, 2, halves), 320, 1},
+		{"jpeg", jpg.Bytes(), 320, 240},
+	}
+	for _, tt := range tests {
+		thumbnail, err := Thumbnail(tt.data, 320)
+		if err != nil {
+			t.Errorf("%s : %v", tt.desc, err)
+			continue
+		}
+		img, format, err := image.Decode(bytes.NewReader(thumbnail))
+		if err != nil || format != "png" {
+			t.Errorf("%s : got a %s thumbnail which does not decode : %v", tt.desc, format, err)
+			continue
+		}
+		if size := img.Bounds().Size(); size.X != tt.width || size.Y != tt.height {
+			t.Errorf("%s : got a %dx%d thumbnail, want %dx%d", tt.desc, size.X, size.Y, tt.width, tt.height)
+		}
+	}
+
+	_, err = Thumbnail([]byte("not an image"), 320)
+	if err == nil {
+		t.Error("got a thumbnail of invalid data")
+	}
+}
+
+func TestThumbnailAverages(t *testing.T) {
+	// Columns black, white, black, white scaled down to 2 then 1 pixels
+	columns := encodeImage(t, 4, 2, func(x, y float64) uint8 {
+		if int(x*4)%2 == 0 {
+			return 0
+		}
+		return 255
+	})
+	gray := func(data []byte, x int) uint8 {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return color.GrayModel.Convert(img.At(x, 0)).(color.Gray).Y
+	}
+
+	thumbnail, err := Thumbnail(columns, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := 0; x < 2; x++ {
+		if y := gray(thumbnail, x); y < 126 || y > 129 {
+			t.Errorf("pixel %d is %d, want the average gray", x, y)
+		}
+	}
+
+	halves := encodeImage(t, 4, 2, func(x, y float64) uint8 {
+		if x < 0.5 {
+			return 0
+		}
+		return 255
+	})
+	thumbnail, err = Thumbnail(halves, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left, right := gray(thumbnail, 0), gray(thumbnail, 1); left != 0 || right != 255 {
+		t.Errorf("got pixels %d and %d, want black and white", left, right)
+	}
+}
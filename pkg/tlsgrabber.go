@@ -0,0 +1,244 @@
+package pkg
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CertificateInfo : details of a certificate of a chain
+type CertificateInfo struct {
+	Subject            string    `bson:"subject" json:"subject"`
+	Issuer             string    `bson:"issuer" json:"issuer"`
+	SANs               []string  `bson:"sans" json:"sans"`
+	NotBefore          time.Time `bson:"notBefore" json:"notBefore"`
+	NotAfter           time.Time `bson:"notAfter" json:"notAfter"`
+	KeyType            string    `bson:"keyType" json:"keyType"`
+	KeySize            int       `bson:"keySize" json:"keySize"`
+	SignatureAlgorithm string    `bson:"signatureAlgorithm" json:"signatureAlgorithm"`
+	SerialNumber       string    `bson:"serialNumber" json:"serialNumber"`
+	Fingerprint        string    `bson:"fingerprint" json:"fingerprint"`
+}
+
+// TLSProtocol : a protocol version accepted by the server and the cipher suite it picked
+type TLSProtocol struct {
+	Version     string `bson:"version" json:"version"`
+	CipherSuite string `bson:"cipherSuite" json:"cipherSuite"`
+	Weak        bool   `bson:"weak" json:"weak"`
+}
+
+// TLSInfo : TLS configuration of a port
+type TLSInfo struct {
+	Chain      []CertificateInfo `bson:"chain" json:"chain"`
+	Protocols  []TLSProtocol     `bson:"protocols" json:"protocols"`
+	Trusted    bool              `bson:"trusted" json:"trusted"`
+	Expired    bool              `bson:"expired" json:"expired"`
+	SelfSigned bool              `bson:"selfSigned" json:"selfSigned"`
+	Weak       bool              `bson:"weak" json:"weak"`
+	Issues     []string          `bson:"issues" json:"issues"`
+}
+
+// tlsVersions : protocol versions tried, oldest first
+var tlsVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+// TLSGrabber : struct for collecting TLS certificates and configuration
+type TLSGrabber struct {
+	timeout time.Duration
+}
+
+// NewTLSGrabber : returns new TLSGrabber
+func NewTLSGrabber(timeout time.Duration) *TLSGrabber {
+	return &TLSGrabber{timeout}
+}
+
+// allCipherSuites : every cipher suite Go knows, including insecure ones, so that they can be detected
+func allCipherSuites() []uint16 {
+	var ids []uint16
+	for _, c := range tls.CipherSuites() {
+		ids = append(ids, c.ID)
+	}
+	for _, c := range tls.InsecureCipherSuites() {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func isInsecureCipherSuite(id uint16) bool {
+	for _, c := range tls.InsecureCipherSuites() {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (g TLSGrabber) handshake(ctx context.Context, host string, port int, version uint16) (*tls.ConnectionState, error) {
+	d := net.Dialer{Timeout: g.timeout}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(g.timeout))
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         host,
+		MinVersion:         version,
+		MaxVersion:         version,
+		CipherSuites:       allCipherSuites(),
+	})
+	err = tlsConn.HandshakeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state := tlsConn.ConnectionState()
+	return &state, nil
+}
+
+// Run : collects the certificate chain and the protocols accepted by a TLS port
+func (g TLSGrabber) Run(ctx context.Context, host string, port int) (*TLSInfo, error) {
+	var state *tls.ConnectionState
+	info := &TLSInfo{}
+
+	for _, version := range tlsVersions {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		s, err := g.handshake(ctx, host, port, version)
+		if err != nil {
+			continue
+		}
+		state = s
+		protocol := TLSProtocol{
+			Version:     tls.VersionName(s.Version),
+			CipherSuite: tls.CipherSuiteName(s.CipherSuite),
+			Weak:        s.Version < tls.VersionTLS12 || isInsecureCipherSuite(s.CipherSuite),
+		}
+		if protocol.Weak {
+			info.Weak = true
+			info.Issues = append(info.Issues, fmt.Sprintf("weak protocol %s with %s", protocol.Version, protocol.CipherSuite))
+		}
+		info.Protocols = append(info.Protocols, protocol)
+	}
+	if state == nil {
+		return nil, fmt.Errorf("no TLS handshake succeeded with %s:%d", host, port)
+	}
+
+	// The state of the most recent protocol holds the chain clients usually get
+	for _, cert := range state.PeerCertificates {
+		info.Chain = append(info.Chain, describeCertificate(cert))
+	}
+	g.analyze(info, host, state.PeerCertificates)
+	return info, nil
+}
+
+// analyze : flags expired, self-signed, untrusted and weak certificates
+func (g TLSGrabber) analyze(info *TLSInfo, host string, certs []*x509.Certificate) {
+	if len(certs) == 0 {
+		info.Issues = append(info.Issues, "no certificate")
+		return
+	}
+
+	now := time.Now()
+	for idx, cert := range certs {
+		if now.After(cert.NotAfter) || now.Before(cert.NotBefore) {
+			info.Expired = true
+			info.Issues = append(info.Issues, fmt.Sprintf("certificate %s is not valid at this date", cert.Subject.String()))
+		}
+		if weak, reason := isWeakCertificate(cert); weak {
+			info.Weak = true
+			info.Issues = append(info.Issues, fmt.Sprintf("certificate %s %s", cert.Subject.String(), reason))
+		}
+		if idx == 0 && cert.CheckSignatureFrom(cert) == nil {
+			info.SelfSigned = true
+			info.Issues = append(info.Issues, "self-signed certificate")
+		}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{DNSName: host, Intermediates: intermediates})
+	info.Trusted = err == nil
+	if err != nil {
+		info.Issues = append(info.Issues, err.Error())
+	}
+}
+
+func isWeakCertificate(cert *x509.Certificate) (bool, string) {
+	switch cert.SignatureAlgorithm {
+	case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		return true, "is signed with " + cert.SignatureAlgorithm.String()
+	}
+	keyType, keySize := publicKeyInfo(cert)
+	if (keyType == "RSA" && keySize < 2048) || (keyType == "ECDSA" && keySize < 256) {
+		return true, fmt.Sprintf("has a weak %s key of %d bits", keyType, keySize)
+	}
+	return false, ""
+}
+
+func publicKeyInfo(cert *x509.Certificate) (string, int) {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA", key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	}
+	return cert.PublicKeyAlgorithm.String(), 0
+}
+
+func describeCertificate(cert *x509.Certificate) CertificateInfo {
+	keyType, keySize := publicKeyInfo(cert)
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	return CertificateInfo{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SANs:               sans,
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		KeyType:            keyType,
+		KeySize:            keySize,
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		SerialNumber:       cert.SerialNumber.String(),
+		Fingerprint:        hex.EncodeToString(fingerprint[:]),
+	}
+}
+
+// CandidateHosts : returns the host names found in the certificate of a port which are not host itself,
+// wildcard names are reduced to their parent domain
+func (info *TLSInfo) CandidateHosts(host string) []string {
+	var ret []string
+	if info == nil || len(info.Chain) == 0 {
+		return ret
+	}
+	seen := map[string]bool{strings.ToLower(host): true}
+	for _, san := range info.Chain[0].SANs {
+		name := strings.ToLower(strings.TrimPrefix(san, "*."))
+		if net.ParseIP(name) != nil || seen[name] {
+			continue
+		}
+		seen[name] = true
+		ret = append(ret, name)
+	}
+	return ret
+}
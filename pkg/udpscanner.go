@@ -0,0 +1,127 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// UDP port states
+const (
+	UDPOpen         = "open"
+	UDPOpenFiltered = "open|filtered"
+	UDPClosed       = "closed"
+)
+
+// udpRetries : probes sent before a silent port is deemed open|filtered
+const udpRetries = 2
+
+// UDPPort : state of a UDP port
+type UDPPort struct {
+	Port  int    `bson:"port" json:"port"`
+	State string `bson:"state" json:"state"`
+}
+
+// udpProbes : payloads eliciting an answer from common UDP services
+var udpProbes = map[int][]byte{
+	// DNS : query of the A record of the root
+	53: {0x13, 0x37, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x01},
+	// TFTP : read request of a random file
+	69: append([]byte{0x00, 0x01}, []byte("faryuk\x00octet\x00")...),
+	// NTP : version 3 client request
+	123: append([]byte{0x1b}, make([]byte, 47)...),
+	// NetBIOS : node status request
+	137: {0x13, 0x37, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x20, 0x43, 0x4b, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41,
+		0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41,
+		0x00, 0x00, 0x21, 0x00, 0x01},
+	// SNMP : v1 get-request of sysDescr with the public community
+	161: {0x30, 0x26, 0x02, 0x01, 0x00, 0x04, 0x06, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0xa0, 0x19, 0x02,
+		0x01, 0x01, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00, 0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06,
+		0x01, 0x02, 0x01, 0x01, 0x01, 0x00, 0x05, 0x00},
+	// IKE : ISAKMP main mode header with an empty SA payload
+	500: {0x13, 0x37, 0x13, 0x37, 0x13, 0x37, 0x13, 0x37, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x01, 0x10, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x28,
+		0x00, 0x00, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01},
+	// SSDP : discovery request
+	1900: []byte("M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nMX: 1\r\nST: ssdp:all\r\n\r\n"),
+	// mDNS : query of the services list
+	5353: {0x13, 0x37, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x09, '_', 's', 'e', 'r', 'v', 'i', 'c', 'e', 's', 0x07, '_', 'd', 'n', 's', '-', 's', 'd',
+		0x04, '_', 'u', 'd', 'p', 0x05, 'l', 'o', 'c', 'a', 'l', 0x00, 0x00, 0x0c, 0x00, 0x01},
+	// Memcached : stats command
+	11211: []byte("\x00\x01\x00\x00\x00\x01\x00\x00stats\r\n"),
+}
+
+// UDPProbe : returns the payload sent to a UDP port, empty when no protocol is known
+func UDPProbe(port int) []byte {
+	return udpProbes[port]
+}
+
+// ProbeUDP : sends a protocol probe to a UDP port and classifies it from the answer
+func (h PortScanner) ProbeUDP(ctx context.Context, port int) string {
+	d := net.Dialer{Timeout: h.timeout}
+	conn, err := d.DialContext(ctx, "udp", h.hostPort(port))
+	if err != nil {
+		return UDPClosed
+	}
+	defer conn.Close()
+
+	stop := make(chan bool)
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	buf := make([]byte, 1500)
+	for i := 0; i < udpRetries && ctx.Err() == nil; i++ {
+		conn.SetDeadline(time.Now().Add(h.timeout))
+		_, err = conn.Write(UDPProbe(port))
+		if err == nil {
+			_, err = conn.Read(buf)
+		}
+		if err == nil {
+			return UDPOpen
+		}
+		// An ICMP port unreachable is reported on the connected socket
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return UDPClosed
+		}
+	}
+	return UDPOpenFiltered
+}
+
+// RunUDP : returns the open and open|filtered ports from a given list, it stops early when ctx is done
+func (h PortScanner) RunUDP(ctx context.Context, ports []int) []UDPPort {
+	rv := []UDPPort{}
+	l := sync.Mutex{}
+	sem := make(chan bool, h.threads)
+loop:
+	for _, port := range ports {
+		select {
+		case <-ctx.Done():
+			break loop
+		case sem <- true:
+		}
+		go func(port int) {
+			state := h.ProbeUDP(ctx, port)
+			if state != UDPClosed && ctx.Err() == nil {
+				l.Lock()
+				rv = append(rv, UDPPort{port, state})
+				l.Unlock()
+			}
+			<-sem
+		}(port)
+	}
+	for i := 0; i < cap(sem); i++ {
+		sem <- true
+	}
+	return rv
+}